@@ -0,0 +1,156 @@
+package goreq
+
+import (
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Backoff Retry delay strategy
+type Backoff interface {
+	// Delay returns time to wait before next attempt
+	// attempt - number of failed attempt starting from 0
+	// response or err - result of failed attempt
+	Delay(attempt uint, response *http.Response, err error) time.Duration
+}
+
+// ConstantBackoff Same delay between all attempts
+type ConstantBackoff struct {
+	// Delay between attempts
+	Interval time.Duration
+}
+
+// Delay returns constant interval
+func (b ConstantBackoff) Delay(attempt uint, response *http.Response, err error) time.Duration {
+	return b.Interval
+}
+
+// LinearBackoff Delay grows on step after each attempt
+// Initial + Step * attempt
+type LinearBackoff struct {
+	// Delay before first retry
+	Initial time.Duration
+	// Increment for each next attempt
+	Step time.Duration
+	// Max delay. 0 - without limit
+	Max time.Duration
+}
+
+// Delay returns linear delay
+func (b LinearBackoff) Delay(attempt uint, response *http.Response, err error) time.Duration {
+	return capDelay(float64(b.Initial)+float64(b.Step)*float64(attempt), b.Max)
+}
+
+// ExponentialBackoff Delay grows exponentially after each attempt
+// Initial * Multiplier ^ attempt
+type ExponentialBackoff struct {
+	// Delay before first retry
+	Initial time.Duration
+	// Growth factor. 2 if not defined
+	Multiplier float64
+	// Max delay. 0 - without limit
+	Max time.Duration
+}
+
+// Delay returns exponential delay
+func (b ExponentialBackoff) Delay(attempt uint, response *http.Response, err error) time.Duration {
+	multiplier := b.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+	return capDelay(float64(b.Initial)*math.Pow(multiplier, float64(attempt)), b.Max)
+}
+
+// FullJitterBackoff Random delay between 0 and Base * 2 ^ attempt
+type FullJitterBackoff struct {
+	// Base delay
+	Base time.Duration
+	// Max delay. 0 - without limit
+	Max time.Duration
+}
+
+// Delay returns random delay in exponential window
+func (b FullJitterBackoff) Delay(attempt uint, response *http.Response, err error) time.Duration {
+	window := capDelay(float64(b.Base)*math.Pow(2, float64(attempt)), b.Max)
+	if window <= 0 {
+		return 0
+	}
+	return rand.N(window + 1)
+}
+
+// DecorrelatedJitterBackoff Random delay between Base and previous delay * 3
+// Previous delays are not stored, chain of delays is recalculated for each attempt
+// so the same value can be shared by concurrent requests
+type DecorrelatedJitterBackoff struct {
+	// Base delay
+	Base time.Duration
+	// Max delay. 0 - without limit
+	Max time.Duration
+}
+
+// Delay returns decorrelated random delay
+func (b DecorrelatedJitterBackoff) Delay(attempt uint, response *http.Response, err error) time.Duration {
+	if b.Base <= 0 {
+		return 0
+	}
+	delay := b.Base
+	for i := uint(0); i <= attempt; i++ {
+		// delay capped by max can be less than base
+		hi := max(3*delay, b.Base)
+		delay = capDelay(float64(b.Base)+float64(rand.N(hi-b.Base+1)), b.Max)
+	}
+	return delay
+}
+
+// Limit delay with max value
+// Huge values are truncated to avoid overflow in next calculations
+func capDelay(delay float64, max time.Duration) time.Duration {
+	if max > 0 && delay > float64(max) {
+		return max
+	}
+	if delay >= math.MaxInt64/4 {
+		return math.MaxInt64 / 4
+	}
+	if delay < 0 {
+		return 0
+	}
+	return time.Duration(delay)
+}
+
+// Delay requested by server in Retry-After or X-RateLimit-Reset headers
+// Retry-After can be defined in seconds or as HTTP-date
+// X-RateLimit-Reset can be defined as delay in seconds or unix timestamp in seconds or milliseconds
+func serverRetryDelay(response *http.Response, now time.Time) (time.Duration, bool) {
+	if response == nil {
+		return 0, false
+	}
+	if value := strings.TrimSpace(response.Header.Get("Retry-After")); value != "" {
+		if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+			return capDelay(seconds*float64(time.Second), 0), true
+		}
+		if date, err := http.ParseTime(value); err == nil {
+			return capDelay(float64(date.Sub(now)), 0), true
+		}
+	}
+	if response.StatusCode != http.StatusTooManyRequests && response.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+	if value := strings.TrimSpace(response.Header.Get("X-RateLimit-Reset")); value != "" {
+		reset, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0, false
+		}
+		switch {
+		case reset > 1e12:
+			return capDelay(float64(time.UnixMilli(int64(reset)).Sub(now)), 0), true
+		case reset > 1e9:
+			return capDelay(float64(time.Unix(int64(reset), 0).Sub(now)), 0), true
+		default:
+			return capDelay(reset*float64(time.Second), 0), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,145 @@
+package goreq
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	if d := (ConstantBackoff{Interval: time.Second}).Delay(5, nil, nil); d != time.Second {
+		t.Fatal("wrong constant delay", d)
+	}
+	linear := LinearBackoff{Initial: time.Second, Step: time.Second, Max: time.Second * 3}
+	if d := linear.Delay(1, nil, nil); d != time.Second*2 {
+		t.Fatal("wrong linear delay", d)
+	}
+	if d := linear.Delay(10, nil, nil); d != time.Second*3 {
+		t.Fatal("linear delay must be capped", d)
+	}
+	exponential := ExponentialBackoff{Initial: time.Millisecond * 100, Max: time.Second}
+	if d := exponential.Delay(2, nil, nil); d != time.Millisecond*400 {
+		t.Fatal("wrong exponential delay", d)
+	}
+	if d := exponential.Delay(1000, nil, nil); d != time.Second {
+		t.Fatal("exponential delay must be capped", d)
+	}
+	if d := (ExponentialBackoff{Initial: time.Second}).Delay(1000, nil, nil); d <= 0 {
+		t.Fatal("exponential delay overflow", d)
+	}
+	jitter := FullJitterBackoff{Base: time.Millisecond * 10, Max: time.Millisecond * 50}
+	decorrelated := DecorrelatedJitterBackoff{Base: time.Millisecond * 10, Max: time.Millisecond * 50}
+	for i := uint(0); i < 100; i++ {
+		if d := jitter.Delay(i, nil, nil); d < 0 || d > time.Millisecond*50 {
+			t.Fatal("wrong full jitter delay", d)
+		}
+		if d := decorrelated.Delay(i, nil, nil); d < time.Millisecond*10 || d > time.Millisecond*50 {
+			t.Fatal("wrong decorrelated jitter delay", d)
+		}
+	}
+	// Max less than base
+	decorrelated = DecorrelatedJitterBackoff{Base: time.Second, Max: time.Millisecond * 100}
+	for i := uint(0); i < 5; i++ {
+		if d := decorrelated.Delay(i, nil, nil); d != time.Millisecond*100 {
+			t.Fatal("delay must be limited with max", d)
+		}
+	}
+}
+
+func TestEnsureBackoff(t *testing.T) {
+	var calls int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+	req := HttpRequest{
+		Method:       http.MethodGet,
+		Url:          s.URL,
+		RetryCount:   3,
+		RetryTimeout: time.Second,
+		Backoff:      ExponentialBackoff{Initial: time.Millisecond * 10},
+	}
+	start := time.Now()
+	response, _, err := Ensure(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != http.StatusOK || calls != 3 {
+		t.Fatal("wrong retry result")
+	}
+	if time.Since(start) > time.Second {
+		t.Fatal("backoff must override retry timeout")
+	}
+}
+
+func TestServerRetryDelay(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		status int
+		header string
+		value  string
+		delay  time.Duration
+		ok     bool
+	}{
+		{http.StatusTooManyRequests, "Retry-After", "3", time.Second * 3, true},
+		{http.StatusServiceUnavailable, "Retry-After", now.Add(time.Minute).Format(http.TimeFormat), time.Minute, true},
+		{http.StatusTooManyRequests, "Retry-After", now.Add(-time.Minute).Format(http.TimeFormat), 0, true},
+		{http.StatusTooManyRequests, "X-RateLimit-Reset", "5", time.Second * 5, true},
+		{http.StatusTooManyRequests, "X-RateLimit-Reset", strconv.FormatInt(now.Add(time.Second*10).Unix(), 10), time.Second * 10, true},
+		{http.StatusTooManyRequests, "X-RateLimit-Reset", strconv.FormatInt(now.Add(time.Second*10).UnixMilli(), 10), time.Second * 10, true},
+		{http.StatusInternalServerError, "X-RateLimit-Reset", "5", 0, false},
+		{http.StatusTooManyRequests, "Retry-After", "soon", 0, false},
+	}
+	for _, c := range cases {
+		response := &http.Response{StatusCode: c.status, Header: http.Header{}}
+		response.Header.Set(c.header, c.value)
+		delay, ok := serverRetryDelay(response, now)
+		if ok != c.ok || delay != c.delay {
+			t.Fatal("wrong server delay", c.header, c.value, delay, ok)
+		}
+	}
+}
+
+func TestEnsureRespectRetryAfter(t *testing.T) {
+	var calls int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+	req := HttpRequest{
+		Method:            http.MethodGet,
+		Url:               s.URL,
+		RetryCount:        1,
+		RespectRetryAfter: true,
+		MaxRetryAfter:     time.Millisecond * 50,
+	}
+	start := time.Now()
+	response, _, err := Ensure(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != http.StatusOK || calls != 2 {
+		t.Fatal("429 must be retried")
+	}
+	if elapsed := time.Since(start); elapsed < time.Millisecond*50 || elapsed > time.Second {
+		t.Fatal("wrong retry after delay", elapsed)
+	}
+	req.RespectRetryAfter = false
+	calls = 0
+	response, _, _ = Ensure(req)
+	if response.StatusCode != http.StatusTooManyRequests || calls != 1 {
+		t.Fatal("429 must not be retried by default")
+	}
+}
@@ -0,0 +1,116 @@
+package goreq
+
+import (
+	"sync"
+	"time"
+)
+
+// Count of buckets in sliding window
+const budgetBuckets = 10
+
+// DefaultBudgetMinRetries Count of retries always allowed in window when MinRetries is not defined
+// Keeps retries of requests with low traffic
+const DefaultBudgetMinRetries = 3
+
+// RetryBudget Limit retries to a ratio of requests in sliding window
+// Budgets are keyed by request label or host if label is empty
+// Budget can be shared between many requests
+type RetryBudget struct {
+	// Allowed ratio of retries to requests. 0.1 if not defined
+	Ratio float64
+	// Count of retries always allowed in window. DefaultBudgetMinRetries if not defined
+	// Without it key with less than 1/Ratio requests in window can not retry
+	// 0 means DefaultBudgetMinRetries, budget limited only by ratio is not supported
+	MinRetries uint
+	// Sliding window. 10s if not defined
+	Window time.Duration
+
+	mu      sync.Mutex
+	windows map[string]*budgetWindow
+}
+
+// Bucket of sliding window
+type budgetBucket struct {
+	// Start of bucket
+	start time.Time
+	// Count of requests
+	requests uint
+	// Count of retries
+	retries uint
+}
+
+// Sliding window of requests and retries
+type budgetWindow struct {
+	buckets [budgetBuckets]budgetBucket
+}
+
+// Register request
+func (b *RetryBudget) request(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.bucket(key, time.Now()).requests++
+}
+
+// Withdraw retry from budget
+// Returns false when budget is exhausted
+func (b *RetryBudget) withdraw(key string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	current := b.bucket(key, now)
+	var requests, retries uint
+	for _, bucket := range b.windows[key].buckets {
+		if now.Sub(bucket.start) < b.window() {
+			requests += bucket.requests
+			retries += bucket.retries
+		}
+	}
+	if float64(retries+1) > b.ratio()*float64(requests)+float64(b.minRetries()) {
+		return false
+	}
+	current.retries++
+	return true
+}
+
+// Get current bucket of window
+func (b *RetryBudget) bucket(key string, now time.Time) *budgetBucket {
+	if b.windows == nil {
+		b.windows = make(map[string]*budgetWindow)
+	}
+	w, ok := b.windows[key]
+	if !ok {
+		w = &budgetWindow{}
+		b.windows[key] = w
+	}
+	size := b.window() / budgetBuckets
+	start := now.Truncate(size)
+	bucket := &w.buckets[(start.UnixNano()/int64(size))%budgetBuckets]
+	if !bucket.start.Equal(start) {
+		*bucket = budgetBucket{start: start}
+	}
+	return bucket
+}
+
+// Ratio with default
+func (b *RetryBudget) ratio() float64 {
+	if b.Ratio <= 0 {
+		return 0.1
+	}
+	return b.Ratio
+}
+
+// Min retries with default
+func (b *RetryBudget) minRetries() uint {
+	if b.MinRetries == 0 {
+		return DefaultBudgetMinRetries
+	}
+	return b.MinRetries
+}
+
+// Window with default
+func (b *RetryBudget) window() time.Duration {
+	if b.Window < budgetBuckets {
+		return time.Second * 10
+	}
+	return b.Window
+}
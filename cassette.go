@@ -0,0 +1,330 @@
+package goreq
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/dimonrus/porterr"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+// CassetteMode Mode of cassette
+type CassetteMode int
+
+const (
+	// CassetteReplay Serve responses from cassette. Unmatched request is an error
+	CassetteReplay CassetteMode = iota
+	// CassetteRecord Perform requests and record responses into cassette
+	CassetteRecord
+	// CassetteAuto Replay if cassette file exists, record otherwise
+	CassetteAuto
+)
+
+// Cassette Record and replay of http interactions for tests
+// Cassette can be shared between many requests
+type Cassette struct {
+	// Path to cassette JSON file
+	Path string
+	// Mode of cassette. CassetteAuto is resolved by LoadCassette
+	Mode CassetteMode
+	// Request matching rules. DefaultCassetteMatcher if not defined
+	Matcher *CassetteMatcher
+	// Redaction policy applied on save and before matching. DefaultRedaction if not defined
+	Redaction *Redaction
+	// Recorded interactions
+	Interactions []CassetteInteraction
+
+	mu sync.Mutex
+	// Replayed interactions
+	used []bool
+}
+
+// CassetteMatcher Rules of request matching in replay mode
+type CassetteMatcher struct {
+	// Match http method
+	Method bool
+	// Match scheme, host and path
+	URL bool
+	// Match query parameters regardless of order
+	Query bool
+	// Match body. JSON bodies are compared by value
+	Body bool
+	// Header names to match
+	Headers []string
+}
+
+// CassetteInteraction Recorded request and response
+type CassetteInteraction struct {
+	// Request
+	Request CassetteRequest `json:"request"`
+	// Response
+	Response CassetteResponse `json:"response"`
+}
+
+// CassetteRequest Recorded request
+type CassetteRequest struct {
+	// Http method
+	Method string `json:"method"`
+	// Full url
+	URL string `json:"url"`
+	// Headers
+	Headers http.Header `json:"headers,omitempty"`
+	// Body
+	Body CassetteBody `json:"body,omitempty"`
+}
+
+// CassetteResponse Recorded response
+type CassetteResponse struct {
+	// Status code
+	Status int `json:"status"`
+	// Headers
+	Headers http.Header `json:"headers,omitempty"`
+	// Body
+	Body CassetteBody `json:"body,omitempty"`
+}
+
+// CassetteBody Body stored as text or base64 for binary data
+type CassetteBody []byte
+
+// MarshalJSON Text body as string, binary as object with base64
+func (b CassetteBody) MarshalJSON() ([]byte, error) {
+	if isBinary(b) {
+		return json.Marshal(struct {
+			Base64 string `json:"base64"`
+		}{Base64: base64.StdEncoding.EncodeToString(b)})
+	}
+	return json.Marshal(string(b))
+}
+
+// UnmarshalJSON Text or base64 body
+func (b *CassetteBody) UnmarshalJSON(data []byte) error {
+	var text string
+	if json.Unmarshal(data, &text) == nil {
+		*b = CassetteBody(text)
+		return nil
+	}
+	var binary struct {
+		Base64 string `json:"base64"`
+	}
+	if err := json.Unmarshal(data, &binary); err != nil {
+		return err
+	}
+	body, err := base64.StdEncoding.DecodeString(binary.Base64)
+	*b = body
+	return err
+}
+
+// DefaultCassetteMatcher Match method, url and query
+func DefaultCassetteMatcher() *CassetteMatcher {
+	return &CassetteMatcher{Method: true, URL: true, Query: true}
+}
+
+// LoadCassette Create cassette and load interactions from file
+// In replay mode file must exist
+func LoadCassette(path string, mode CassetteMode) (*Cassette, porterr.IError) {
+	c := &Cassette{Path: path, Mode: mode}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) && mode != CassetteReplay {
+			c.Mode = CassetteRecord
+			return c, nil
+		}
+		return nil, porterr.NewF(porterr.PortErrorIO, "Cassette (%s) read error: %s", path, err)
+	}
+	if mode == CassetteRecord {
+		return c, nil
+	}
+	var file struct {
+		Interactions []CassetteInteraction `json:"interactions"`
+	}
+	if err = json.Unmarshal(data, &file); err != nil {
+		return nil, porterr.NewF(porterr.PortErrorParser, "Cassette (%s) parse error: %s", path, err)
+	}
+	c.Interactions = file.Interactions
+	c.Mode = CassetteReplay
+	return c, nil
+}
+
+// Save Write recorded interactions into file
+// Sensitive data is masked by redaction policy
+func (c *Cassette) Save() porterr.IError {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	file := struct {
+		Interactions []CassetteInteraction `json:"interactions"`
+	}{Interactions: make([]CassetteInteraction, len(c.Interactions))}
+	redaction := c.redaction()
+	for i, interaction := range c.Interactions {
+		interaction.Request = redaction.cassetteRequest(interaction.Request)
+		interaction.Response.Headers = redaction.cassetteHeaders(interaction.Response.Headers)
+		interaction.Response.Body = redaction.Body(interaction.Response.Body, interaction.Response.Headers)
+		file.Interactions[i] = interaction
+	}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return porterr.NewF(porterr.PortErrorParser, "Cassette (%s) marshal error: %s", c.Path, err)
+	}
+	if err = os.WriteFile(c.Path, data, 0o644); err != nil {
+		return porterr.NewF(porterr.PortErrorIO, "Cassette (%s) write error: %s", c.Path, err)
+	}
+	return nil
+}
+
+// Redaction policy with default
+func (c *Cassette) redaction() *Redaction {
+	if c.Redaction == nil {
+		return defaultRedaction
+	}
+	return c.Redaction
+}
+
+// Matcher with default
+func (c *Cassette) matcher() *CassetteMatcher {
+	if c.Matcher == nil {
+		return DefaultCassetteMatcher()
+	}
+	return c.Matcher
+}
+
+// Record interaction
+func (c *Cassette) record(interaction CassetteInteraction) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Interactions = append(c.Interactions, interaction)
+}
+
+// Find first not replayed interaction matched with request
+func (c *Cassette) replay(request CassetteRequest) (CassetteResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.used) != len(c.Interactions) {
+		c.used = make([]bool, len(c.Interactions))
+	}
+	request = c.redaction().cassetteRequest(request)
+	matcher := c.matcher()
+	for i, interaction := range c.Interactions {
+		if !c.used[i] && matcher.match(request, interaction.Request) {
+			c.used[i] = true
+			return interaction.Response, true
+		}
+	}
+	return CassetteResponse{}, false
+}
+
+// Check if request matches recorded one
+func (m *CassetteMatcher) match(request CassetteRequest, recorded CassetteRequest) bool {
+	if m.Method && request.Method != recorded.Method {
+		return false
+	}
+	if m.URL || m.Query {
+		requestURL, err := url.Parse(request.URL)
+		if err != nil {
+			return false
+		}
+		recordedURL, err := url.Parse(recorded.URL)
+		if err != nil {
+			return false
+		}
+		if m.URL && (requestURL.Scheme != recordedURL.Scheme || requestURL.Host != recordedURL.Host || requestURL.Path != recordedURL.Path) {
+			return false
+		}
+		if m.Query && !reflect.DeepEqual(requestURL.Query(), recordedURL.Query()) {
+			return false
+		}
+	}
+	for _, name := range m.Headers {
+		if !reflect.DeepEqual(request.Headers.Values(name), recorded.Headers.Values(name)) {
+			return false
+		}
+	}
+	return !m.Body || equalBody(request.Body, recorded.Body)
+}
+
+// Compare bodies. JSON bodies are compared by value
+func equalBody(a []byte, b []byte) bool {
+	if bytes.Equal(a, b) {
+		return true
+	}
+	var first, second interface{}
+	if json.Unmarshal(a, &first) != nil || json.Unmarshal(b, &second) != nil {
+		return false
+	}
+	return reflect.DeepEqual(first, second)
+}
+
+// Mask sensitive data of recorded request
+func (p *Redaction) cassetteRequest(request CassetteRequest) CassetteRequest {
+	request.URL = p.URL(request.URL)
+	request.Headers = p.cassetteHeaders(request.Headers)
+	request.Body = p.Body(request.Body, request.Headers)
+	return request
+}
+
+// Mask sensitive headers
+func (p *Redaction) cassetteHeaders(header http.Header) http.Header {
+	if header == nil {
+		return nil
+	}
+	result := make(http.Header, len(header))
+	for name, values := range header {
+		for _, value := range values {
+			result[name] = append(result[name], p.Header(name, value))
+		}
+	}
+	return result
+}
+
+// Cassette middleware
+// Replaces transport in replay mode and records responses in record mode
+func cassetteMiddleware(request *HttpRequest) Middleware {
+	return func(next Handler) Handler {
+		cassette := request.Cassette
+		if cassette == nil {
+			return next
+		}
+		return func(req *http.Request) (*http.Response, error) {
+			recorded := CassetteRequest{Method: req.Method, URL: req.URL.String(), Headers: req.Header.Clone()}
+			if req.GetBody != nil {
+				if body, err := req.GetBody(); err == nil {
+					recorded.Body, _ = io.ReadAll(body)
+				}
+			}
+			if cassette.Mode == CassetteReplay {
+				response, ok := cassette.replay(recorded)
+				if !ok {
+					return nil, porterr.NewF(porterr.PortErrorRequest, "Cassette (%s) has no interaction for request %s %s. Service: %s",
+						cassette.Path, req.Method, request.redaction().URL(req.URL.String()), request.Label).HTTP(http.StatusNotImplemented)
+				}
+				return &http.Response{
+					Status:        strconv.Itoa(response.Status) + " " + http.StatusText(response.Status),
+					StatusCode:    response.Status,
+					Proto:         "HTTP/1.1",
+					ProtoMajor:    1,
+					ProtoMinor:    1,
+					Header:        response.Headers.Clone(),
+					Body:          newBufferedBody(response.Body),
+					ContentLength: int64(len(response.Body)),
+					Request:       req,
+				}, nil
+			}
+			response, err := next(req)
+			if err != nil {
+				return response, err
+			}
+			body, _ := ResponseBody(response)
+			cassette.record(CassetteInteraction{
+				Request:  recorded,
+				Response: CassetteResponse{Status: response.StatusCode, Headers: response.Header.Clone(), Body: bytes.Clone(body)},
+			})
+			return response, nil
+		}
+	}
+}
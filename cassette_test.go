@@ -0,0 +1,84 @@
+package goreq
+
+import (
+	"github.com/dimonrus/porterr"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCassette(t *testing.T) {
+	var calls int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":` + r.URL.Query().Get("id") + `,"token":"secret"}`))
+	}))
+	defer s.Close()
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	req := HttpRequest{
+		Method:     http.MethodPost,
+		Host:       s.URL,
+		Url:        "/items?id=1&api_key=secret",
+		Headers:    http.Header{"Authorization": {"Bearer secret"}, "Content-Type": {"application/json"}},
+		Body:       []byte(`{"name":"item"}`),
+		RetryCount: 1,
+	}
+	// Record
+	cassette, e := LoadCassette(path, CassetteAuto)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if cassette.Mode != CassetteRecord {
+		t.Fatal("cassette must record when file does not exist")
+	}
+	req.Cassette = cassette
+	if _, _, err := Ensure(req); err != nil {
+		t.Fatal(err)
+	}
+	if e = cassette.Save(); e != nil {
+		t.Fatal(e)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Fatal("sensitive data must be scrubbed", string(data))
+	}
+	// Replay
+	cassette, e = LoadCassette(path, CassetteAuto)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if cassette.Mode != CassetteReplay || len(cassette.Interactions) != 2 {
+		t.Fatal("cassette must replay recorded interactions", cassette.Mode, len(cassette.Interactions))
+	}
+	cassette.Matcher = &CassetteMatcher{Method: true, URL: true, Query: true, Body: true, Headers: []string{"Content-Type"}}
+	req.Cassette = cassette
+	req.Url = "/items?api_key=other&id=1"
+	req.Body = []byte(`{ "name": "item" }`)
+	response, body, err := Ensure(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != http.StatusOK || string(body) != `{"id":1,"token":"***"}` || atomic.LoadInt32(&calls) != 2 {
+		t.Fatal("wrong replay", response.StatusCode, string(body), calls)
+	}
+	// Unmatched request
+	req.Url = "/items?id=2"
+	_, _, err = Ensure(req)
+	if e, ok := err.(porterr.IError); !ok || e.GetHTTP() != http.StatusNotImplemented || !strings.Contains(e.Error(), "no interaction") {
+		t.Fatal("unmatched request must fail", err)
+	}
+	if _, e = LoadCassette(filepath.Join(t.TempDir(), "none.json"), CassetteReplay); e == nil {
+		t.Fatal("replay requires cassette file")
+	}
+}
@@ -0,0 +1,288 @@
+package goreq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/dimonrus/gorest"
+	"github.com/dimonrus/porterr"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Checkpoint Progress of paginator
+type Checkpoint struct {
+	// Paginator form
+	Form json.RawMessage `json:"form,omitempty"`
+	// Meta of the first page
+	Meta gorest.Meta `json:"meta"`
+	// Completed pages ordered by page
+	Pages []int `json:"pages,omitempty"`
+	// Cursor of the next page of cursor paginator
+	Cursor string `json:"cursor,omitempty"`
+	// All pages are completed
+	Done bool `json:"done"`
+	// Time of save
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// CheckpointStore Storage of paginator checkpoints
+type CheckpointStore interface {
+	// Load checkpoint by key. Returns nil if checkpoint does not exist
+	Load(key string) (*Checkpoint, error)
+	// Save checkpoint by key
+	Save(key string, checkpoint Checkpoint) error
+}
+
+// FileCheckpointStore Checkpoint store with JSON file per key
+// Key must be a valid file name
+type FileCheckpointStore struct {
+	// Directory of checkpoint files
+	Dir string
+}
+
+// Load checkpoint from file
+func (s FileCheckpointStore) Load(key string) (*Checkpoint, error) {
+	data, err := os.ReadFile(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var checkpoint Checkpoint
+	if err = json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+// Save checkpoint into file
+// File is replaced atomically
+func (s FileCheckpointStore) Save(key string, checkpoint Checkpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(s.Dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), s.path(key))
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+	}
+	return err
+}
+
+// Path of checkpoint file
+func (s FileCheckpointStore) path(key string) string {
+	return filepath.Join(s.Dir, key+".json")
+}
+
+// Checkpointer Periodic saving of paginator progress
+type Checkpointer struct {
+	// Checkpoint store
+	Store CheckpointStore
+	// Key of checkpoint
+	Key string
+	// Save checkpoint every N completed pages. Every page if not defined
+	Every int
+}
+
+// Save checkpoint
+func (c Checkpointer) save(checkpoint *Checkpoint) porterr.IError {
+	sort.Ints(checkpoint.Pages)
+	checkpoint.UpdatedAt = time.Now()
+	if err := c.Store.Save(c.Key, *checkpoint); err != nil {
+		return porterr.NewF(porterr.PortErrorIO, "Checkpoint (%s) save error: %s", c.Key, err)
+	}
+	return nil
+}
+
+// Load checkpoint
+// Error if checkpoint does not exist
+func (c Checkpointer) load() (*Checkpoint, porterr.IError) {
+	checkpoint, err := c.Store.Load(c.Key)
+	if err != nil {
+		return nil, porterr.NewF(porterr.PortErrorIO, "Checkpoint (%s) load error: %s", c.Key, err)
+	}
+	if checkpoint == nil {
+		return nil, porterr.NewF(porterr.PortErrorLoad, "Checkpoint (%s) does not exist", c.Key)
+	}
+	return checkpoint, nil
+}
+
+// Check if checkpoint must be saved after count of completed pages
+func (c Checkpointer) due(completed int) bool {
+	return completed >= max(c.Every, 1)
+}
+
+// ParallelPaginatorJsonEnsureCheckpoint Execute api call of paginator and pass each page to handler in order of pages
+// Progress is saved with checkpointer and can be continued with ResumeParallelPaginatorJson
+// Checkpoint is saved before the first page
+// Pages are requested in parallel with window of form parallel count
+func ParallelPaginatorJsonEnsureCheckpoint[F any, R any](ctx context.Context, form F, hr HttpRequest, checkpointer Checkpointer, handler func(page int, items []R) error) porterr.IError {
+	data, err := json.Marshal(form)
+	if err != nil {
+		return porterr.NewF(porterr.PortErrorBody, "Checkpoint (%s) form marshal error: %s", checkpointer.Key, err)
+	}
+	checkpoint := &Checkpoint{Form: data}
+	if e := checkpointer.save(checkpoint); e != nil {
+		return e
+	}
+	return paginatorCheckpoint[F, R](ctx, form, hr, checkpoint, checkpointer, handler)
+}
+
+// ResumeParallelPaginatorJson Continue paginator from the last checkpoint
+// Form is restored from checkpoint, completed pages are not requested
+func ResumeParallelPaginatorJson[F any, R any](ctx context.Context, hr HttpRequest, checkpointer Checkpointer, handler func(page int, items []R) error) porterr.IError {
+	checkpoint, e := checkpointer.load()
+	if e != nil {
+		return e
+	}
+	var form F
+	if err := json.Unmarshal(checkpoint.Form, &form); err != nil {
+		return porterr.NewF(porterr.PortErrorBody, "Checkpoint (%s) form unmarshal error: %s", checkpointer.Key, err)
+	}
+	return paginatorCheckpoint[F, R](ctx, form, hr, checkpoint, checkpointer, handler)
+}
+
+// Request not completed pages of checkpoint
+func paginatorCheckpoint[F any, R any](ctx context.Context, form F, hr HttpRequest, checkpoint *Checkpoint, checkpointer Checkpointer, handler func(page int, items []R) error) (e porterr.IError) {
+	var _f interface{} = &form
+	var _form, ok = _f.(IPaginator)
+	if !ok {
+		return porterr.New(porterr.PortErrorRequest, "form must implements IPaginator interface")
+	}
+	completed := make(map[int]struct{}, len(checkpoint.Pages))
+	for _, page := range checkpoint.Pages {
+		completed[page] = struct{}{}
+	}
+	var unsaved int
+	complete := func(page int, items []R) porterr.IError {
+		if err := handler(page, items); err != nil {
+			return porterr.NewF(porterr.PortErrorHandler, "Paginator page %d handler error: %s", page, err)
+		}
+		completed[page] = struct{}{}
+		checkpoint.Pages = append(checkpoint.Pages, page)
+		unsaved++
+		if checkpointer.due(unsaved) {
+			unsaved = 0
+			return checkpointer.save(checkpoint)
+		}
+		return nil
+	}
+	defer func() {
+		if unsaved > 0 || e == nil {
+			if err := checkpointer.save(checkpoint); e == nil {
+				e = err
+			}
+		}
+	}()
+	// First page defines count of pages
+	if checkpoint.Meta.Page == 0 {
+		items, meta, err := paginatorPage[F, R](ctx, hr, form)
+		if err != nil {
+			return err
+		}
+		if meta.Page == 0 {
+			meta.Page = 1
+		}
+		checkpoint.Meta = meta
+		if e = complete(meta.Page, items); e != nil {
+			return
+		}
+	}
+	meta := checkpoint.Meta
+	var pages []int
+	for page := meta.Page; page == meta.Page || (meta.Limit > 0 && (page-1)*meta.Limit < meta.Total); page++ {
+		if _, ok := completed[page]; !ok {
+			pages = append(pages, page)
+		}
+	}
+	fetchPages[F, R](ctx, hr, form, pages, _form.GetParallelCount(), func(page int, items []R, err porterr.IError) bool {
+		if err == nil {
+			err = complete(page, items)
+		}
+		e = err
+		return e == nil
+	})
+	checkpoint.Done = e == nil
+	return e
+}
+
+// CursorPaginatorEnsureCheckpoint Request pages of cursor paginator and pass each page to handler
+// Cursor of the next page is saved with checkpointer and can be continued with ResumeCursorPaginator
+// Returns cursor of the next page which is empty when all pages were fetched
+func CursorPaginatorEnsureCheckpoint[R any](ctx context.Context, hr HttpRequest, paginator CursorPaginator[R], checkpointer Checkpointer, handler func(items []R) error) (cursor string, e porterr.IError) {
+	if paginator.Extract == nil || paginator.Inject == nil {
+		e = porterr.New(porterr.PortErrorRequest, "cursor paginator must have extractor and injector")
+		return
+	}
+	checkpoint := &Checkpoint{Cursor: paginator.Cursor}
+	cursor = paginator.Cursor
+	if e = checkpointer.save(checkpoint); e != nil {
+		return
+	}
+	var unsaved int
+	defer func() {
+		if unsaved > 0 || e == nil {
+			if err := checkpointer.save(checkpoint); e == nil {
+				e = err
+			}
+		}
+	}()
+	seen := make(map[string]struct{})
+	for page := 0; paginator.MaxPages == 0 || page < paginator.MaxPages; page++ {
+		var items []R
+		var next string
+		items, next, e = paginator.fetch(ctx, hr, cursor)
+		if e != nil {
+			return
+		}
+		if err := handler(items); err != nil {
+			e = porterr.NewF(porterr.PortErrorHandler, "Paginator cursor %s handler error: %s", cursor, err)
+			return
+		}
+		cursor = next
+		checkpoint.Cursor = cursor
+		checkpoint.Done = cursor == ""
+		unsaved++
+		if cursor == "" {
+			return
+		}
+		if _, ok := seen[cursor]; ok {
+			e = porterr.NewF(porterr.PortErrorResponse, "Http Request (%s) cursor %s repeats. Service: %s", hr.Url, cursor, hr.Label)
+			return
+		}
+		seen[cursor] = struct{}{}
+		if checkpointer.due(unsaved) {
+			unsaved = 0
+			if e = checkpointer.save(checkpoint); e != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// ResumeCursorPaginator Continue cursor paginator from the last checkpoint
+// Paginator cursor is replaced with the saved one
+func ResumeCursorPaginator[R any](ctx context.Context, hr HttpRequest, paginator CursorPaginator[R], checkpointer Checkpointer, handler func(items []R) error) (cursor string, e porterr.IError) {
+	checkpoint, e := checkpointer.load()
+	if e != nil || checkpoint.Done {
+		return
+	}
+	paginator.Cursor = checkpoint.Cursor
+	return CursorPaginatorEnsureCheckpoint(ctx, hr, paginator, checkpointer, handler)
+}
@@ -0,0 +1,134 @@
+package goreq
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestParallelPaginatorJsonEnsureCheckpoint(t *testing.T) {
+	var mu sync.Mutex
+	requested := make(map[int]int)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var p Paginator
+		_ = json.Unmarshal(data, &p)
+		mu.Lock()
+		requested[p.Page]++
+		mu.Unlock()
+		r.Body = io.NopCloser(bytes.NewReader(data))
+		testPaginatorHandler(w, r)
+	}))
+	defer s.Close()
+	var total = 100
+	var limit = 10
+	hr := HttpRequest{Method: http.MethodPost, Url: s.URL + fmt.Sprintf("/?total=%v", total)}
+	form := PaginatorRequestForm{Name: "item", Paginator: Paginator{Page: 1, Limit: limit, ParallelCount: 3}}
+	checkpointer := Checkpointer{Store: FileCheckpointStore{Dir: t.TempDir()}, Key: "export", Every: 2}
+
+	if e := ResumeParallelPaginatorJson[PaginatorRequestForm, PaginatorTestItem](context.Background(), hr, checkpointer, nil); e == nil {
+		t.Fatal("missing checkpoint must be an error")
+	}
+
+	var numbers []int
+	var crashed bool
+	handler := func(page int, items []PaginatorTestItem) error {
+		if page == 6 && !crashed {
+			crashed = true
+			return errors.New("crash")
+		}
+		for _, item := range items {
+			numbers = append(numbers, item.Number)
+		}
+		return nil
+	}
+	e := ParallelPaginatorJsonEnsureCheckpoint[PaginatorRequestForm, PaginatorTestItem](context.Background(), form, hr, checkpointer, handler)
+	if e == nil {
+		t.Fatal("handler error must be returned")
+	}
+	checkpoint, err := checkpointer.Store.Load(checkpointer.Key)
+	if err != nil || checkpoint == nil || checkpoint.Done || len(checkpoint.Pages) != 5 || checkpoint.Meta.Total != total {
+		t.Fatal("wrong checkpoint", checkpoint, err)
+	}
+
+	mu.Lock()
+	clear(requested)
+	mu.Unlock()
+	e = ResumeParallelPaginatorJson[PaginatorRequestForm, PaginatorTestItem](context.Background(), hr, checkpointer, handler)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if len(numbers) != total {
+		t.Fatal("all items must be handled", len(numbers))
+	}
+	for i, number := range numbers {
+		if number != i {
+			t.Fatal("items must be ordered", numbers)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for page := range requested {
+		if page <= 5 {
+			t.Fatal("completed pages must not be requested", requested)
+		}
+	}
+	checkpoint, _ = checkpointer.Store.Load(checkpointer.Key)
+	if !checkpoint.Done || len(checkpoint.Pages) != 10 {
+		t.Fatal("checkpoint must be done", checkpoint)
+	}
+}
+
+func TestCursorPaginatorEnsureCheckpoint(t *testing.T) {
+	var requested []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cursor := r.URL.Query().Get("after")
+		requested = append(requested, cursor)
+		start, _ := strconv.Atoi(cursor)
+		next := ""
+		if start+1 < 5 {
+			next = strconv.Itoa(start + 1)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": []int{start}, "next": next})
+	}))
+	defer s.Close()
+	hr := HttpRequest{Method: http.MethodGet, Host: s.URL, Url: "/items"}
+	paginator := CursorPaginator[int]{Extract: JSONCursor[int]("data", "next"), Inject: CursorQuery("after")}
+	checkpointer := Checkpointer{Store: FileCheckpointStore{Dir: t.TempDir()}, Key: "cursor"}
+
+	var items []int
+	var crashed bool
+	handler := func(page []int) error {
+		if page[0] == 3 && !crashed {
+			crashed = true
+			return errors.New("crash")
+		}
+		items = append(items, page...)
+		return nil
+	}
+	cursor, e := CursorPaginatorEnsureCheckpoint(context.Background(), hr, paginator, checkpointer, handler)
+	if e == nil || cursor != "3" {
+		t.Fatal("handler error must be returned", cursor, e)
+	}
+	requested = nil
+	cursor, e = ResumeCursorPaginator(context.Background(), hr, paginator, checkpointer, handler)
+	if e != nil || cursor != "" {
+		t.Fatal(cursor, e)
+	}
+	if len(items) != 5 || requested[0] != "3" || len(requested) != 2 {
+		t.Fatal("pagination must continue from checkpoint", items, requested)
+	}
+	// Completed paginator is not requested again
+	requested = nil
+	if _, e = ResumeCursorPaginator(context.Background(), hr, paginator, checkpointer, handler); e != nil || len(requested) != 0 {
+		t.Fatal("completed checkpoint must not be requested", requested, e)
+	}
+}
@@ -0,0 +1,249 @@
+package goreq
+
+import (
+	"context"
+	"github.com/dimonrus/porterr"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultCircuitMinRequests Min count of requests before failure ratio is checked when MinRequests is not defined
+// Keeps retries of request when the first attempts fail
+const DefaultCircuitMinRequests = 20
+
+// CircuitState State of circuit
+type CircuitState int
+
+const (
+	// CircuitClosed Requests are allowed
+	CircuitClosed CircuitState = iota
+	// CircuitOpen Requests are rejected
+	CircuitOpen
+	// CircuitHalfOpen Limited count of probe requests are allowed
+	CircuitHalfOpen
+)
+
+// String name of state
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreaker Stop requests to failed service
+// Circuits are keyed by request label or host if label is empty
+// Breaker can be shared between many requests
+type CircuitBreaker struct {
+	// Ratio of failed requests to open the circuit. 0.5 if not defined
+	FailureRatio float64
+	// Min count of requests before failure ratio is checked. DefaultCircuitMinRequests if not defined
+	MinRequests uint
+	// Period of request counting in closed state. 0 - counters reset only on state change
+	Interval time.Duration
+	// Time in open state before probe requests. Default 30s
+	OpenDuration time.Duration
+	// Count of successful probe requests in half-open state to close the circuit. 1 if not defined
+	HalfOpenProbes uint
+	// Callback on state change
+	OnStateChange func(key string, from CircuitState, to CircuitState)
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+// Circuit counters
+type circuit struct {
+	// Current state
+	state CircuitState
+	// Count of requests in closed state
+	requests uint
+	// Count of failures in closed state
+	failures uint
+	// Count of probes in progress
+	probes uint
+	// Count of successful probes
+	successes uint
+	// Time of state or counters expiration
+	expiry time.Time
+}
+
+// State Get current state of circuit
+func (b *CircuitBreaker) State(key string) CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuit(key)
+	if c.state == CircuitOpen && !time.Now().Before(c.expiry) {
+		return CircuitHalfOpen
+	}
+	return c.state
+}
+
+// Check if request is allowed
+func (b *CircuitBreaker) allow(key string) bool {
+	b.mu.Lock()
+	c := b.circuit(key)
+	from := c.state
+	now := time.Now()
+	switch c.state {
+	case CircuitClosed:
+		if b.Interval > 0 && !now.Before(c.expiry) {
+			c.requests, c.failures = 0, 0
+			c.expiry = now.Add(b.Interval)
+		}
+		b.mu.Unlock()
+		return true
+	case CircuitOpen:
+		if now.Before(c.expiry) {
+			b.mu.Unlock()
+			return false
+		}
+		b.setState(c, CircuitHalfOpen, now)
+	}
+	allowed := c.probes < b.halfOpenProbes()
+	if allowed {
+		c.probes++
+	}
+	to := c.state
+	b.mu.Unlock()
+	b.notify(key, from, to)
+	return allowed
+}
+
+// Report result of allowed request
+func (b *CircuitBreaker) report(key string, failed bool) {
+	b.mu.Lock()
+	c := b.circuit(key)
+	from := c.state
+	switch c.state {
+	case CircuitClosed:
+		c.requests++
+		if failed {
+			c.failures++
+		}
+		if failed && c.requests >= b.minRequests() && float64(c.failures) >= b.failureRatio()*float64(c.requests) {
+			b.setState(c, CircuitOpen, time.Now())
+		}
+	case CircuitHalfOpen:
+		if c.probes > 0 {
+			c.probes--
+		}
+		if failed {
+			b.setState(c, CircuitOpen, time.Now())
+		} else {
+			c.successes++
+			if c.successes >= b.halfOpenProbes() {
+				b.setState(c, CircuitClosed, time.Now())
+			}
+		}
+	}
+	to := c.state
+	b.mu.Unlock()
+	b.notify(key, from, to)
+}
+
+// Release allowed request without result
+// Used when request is canceled by caller
+func (b *CircuitBreaker) release(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuit(key)
+	if c.state == CircuitHalfOpen && c.probes > 0 {
+		c.probes--
+	}
+}
+
+// Get or create circuit
+func (b *CircuitBreaker) circuit(key string) *circuit {
+	if b.circuits == nil {
+		b.circuits = make(map[string]*circuit)
+	}
+	c, ok := b.circuits[key]
+	if !ok {
+		c = &circuit{}
+		if b.Interval > 0 {
+			c.expiry = time.Now().Add(b.Interval)
+		}
+		b.circuits[key] = c
+	}
+	return c
+}
+
+// Change state and reset counters
+func (b *CircuitBreaker) setState(c *circuit, state CircuitState, now time.Time) {
+	c.state = state
+	c.requests, c.failures, c.probes, c.successes = 0, 0, 0, 0
+	switch state {
+	case CircuitOpen:
+		c.expiry = now.Add(b.openDuration())
+	case CircuitClosed:
+		c.expiry = time.Time{}
+		if b.Interval > 0 {
+			c.expiry = now.Add(b.Interval)
+		}
+	}
+}
+
+// Call state change callback
+func (b *CircuitBreaker) notify(key string, from CircuitState, to CircuitState) {
+	if from != to && b.OnStateChange != nil {
+		b.OnStateChange(key, from, to)
+	}
+}
+
+// Failure ratio with default
+func (b *CircuitBreaker) failureRatio() float64 {
+	if b.FailureRatio <= 0 {
+		return 0.5
+	}
+	return b.FailureRatio
+}
+
+// Open duration with default
+func (b *CircuitBreaker) openDuration() time.Duration {
+	if b.OpenDuration <= 0 {
+		return time.Second * DefaultTimeout
+	}
+	return b.OpenDuration
+}
+
+// Min count of requests with default
+func (b *CircuitBreaker) minRequests() uint {
+	if b.MinRequests == 0 {
+		return DefaultCircuitMinRequests
+	}
+	return b.MinRequests
+}
+
+// Count of half-open probes with default
+func (b *CircuitBreaker) halfOpenProbes() uint {
+	if b.HalfOpenProbes == 0 {
+		return 1
+	}
+	return b.HalfOpenProbes
+}
+
+// Report attempt result to circuit breaker
+// 5xx responses and transport errors are failures
+func (r *HttpRequest) reportCircuit(ctx context.Context, key string, response *http.Response, err error) {
+	if r.CircuitBreaker == nil {
+		return
+	}
+	if ctx.Err() != nil {
+		r.CircuitBreaker.release(key)
+		return
+	}
+	r.CircuitBreaker.report(key, err != nil || response.StatusCode >= http.StatusInternalServerError)
+}
+
+// Error when circuit is open
+func circuitOpenError(request *HttpRequest, key string) porterr.IError {
+	return porterr.NewFWithName(PortErrorCircuitOpen, key, "Http Request (%s) rejected. Circuit is open. Service: %s", request.Url, request.Label).HTTP(http.StatusServiceUnavailable)
+}
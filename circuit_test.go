@@ -0,0 +1,86 @@
+package goreq
+
+import (
+	"github.com/dimonrus/porterr"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var healthy atomic.Bool
+	var calls int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+	var mu sync.Mutex
+	var changes []CircuitState
+	breaker := &CircuitBreaker{
+		MinRequests:  2,
+		OpenDuration: time.Millisecond * 100,
+		OnStateChange: func(key string, from CircuitState, to CircuitState) {
+			mu.Lock()
+			changes = append(changes, to)
+			mu.Unlock()
+		},
+	}
+	req := HttpRequest{
+		Method:         http.MethodGet,
+		Url:            s.URL,
+		Label:          "circuit",
+		RetryCount:     5,
+		CircuitBreaker: breaker,
+	}
+	_, _, err := Ensure(req)
+	if err == nil || err.(porterr.IError).GetCode() != PortErrorCircuitOpen {
+		t.Fatal("circuit must be open", err)
+	}
+	if calls != 2 || breaker.State("circuit") != CircuitOpen {
+		t.Fatal("wrong count of calls before circuit open", calls)
+	}
+	_, _, err = Ensure(req)
+	if err == nil || calls != 2 {
+		t.Fatal("request must be rejected")
+	}
+	healthy.Store(true)
+	time.Sleep(time.Millisecond * 110)
+	if breaker.State("circuit") != CircuitHalfOpen {
+		t.Fatal("circuit must be half-open")
+	}
+	if _, _, err = Ensure(req); err != nil {
+		t.Fatal(err)
+	}
+	if breaker.State("circuit") != CircuitClosed {
+		t.Fatal("circuit must be closed")
+	}
+	if len(changes) != 3 || changes[0] != CircuitOpen || changes[1] != CircuitHalfOpen || changes[2] != CircuitClosed {
+		t.Fatal("wrong state changes", changes)
+	}
+}
+
+func TestCircuitBreakerDefault(t *testing.T) {
+	var calls int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer s.Close()
+	breaker := &CircuitBreaker{}
+	req := HttpRequest{Method: http.MethodGet, Url: s.URL, RetryCount: 3, CircuitBreaker: breaker}
+	response, _, err := Ensure(req)
+	if IsCircuitOpen(err) || response == nil || response.StatusCode != http.StatusServiceUnavailable {
+		t.Fatal("retries must not be rejected by circuit", err)
+	}
+	if atomic.LoadInt32(&calls) != 4 {
+		t.Fatal("all retries must be used", calls)
+	}
+}
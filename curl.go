@@ -0,0 +1,195 @@
+package goreq
+
+import (
+	"encoding/base64"
+	"golang.org/x/net/http2"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Request prepared for command building
+type commandRequest struct {
+	// Http method
+	method string
+	// Full url
+	url string
+	// Sorted header names
+	names []string
+	// Headers
+	headers http.Header
+	// Body
+	body []byte
+	// Body is binary
+	binary bool
+	// Size of origin body when body is truncated
+	truncated int
+	// Client uses HTTP/2
+	http2 bool
+}
+
+// Prepare request for command building
+// Sensitive data is masked, body is truncated to LogBodySize
+func newCommandRequest(request HttpRequest) commandRequest {
+	c := commandRequest{
+		method:  request.Method,
+		url:     request.redaction().URL(request.Host + request.Url),
+		headers: make(http.Header, len(request.Headers)),
+	}
+	for k, v := range request.Headers {
+		c.names = append(c.names, k)
+		for _, value := range v {
+			c.headers[k] = append(c.headers[k], request.redaction().Header(k, value))
+		}
+	}
+	sort.Strings(c.names)
+	if request.Body != nil {
+		c.body = request.redaction().Body(request.Body, request.Headers)
+		c.binary = isBinary(c.body)
+		if request.LogBodySize > 0 && len(c.body) > request.LogBodySize {
+			c.truncated = len(c.body)
+			c.body = truncateBody(c.body, request.LogBodySize, c.binary)
+		}
+	}
+	if request.Client != nil {
+		_, c.http2 = request.Client.Transport.(*http2.Transport)
+	}
+	return c
+}
+
+// Marker of truncated body
+func (c commandRequest) truncatedMarker() string {
+	if c.truncated == 0 {
+		return ""
+	}
+	return " # body truncated to " + strconv.Itoa(len(c.body)) + " of " + strconv.Itoa(c.truncated) + " bytes"
+}
+
+// BuildCURL Build curl command for logging
+// Command can be pasted into POSIX shell
+// Binary body is passed as base64 via stdin
+// Sensitive data is masked with DefaultRedaction if request Redaction is not defined
+// Use empty &Redaction{} to get command which replays the exact request
+func BuildCURL(request HttpRequest) string {
+	c := newCommandRequest(request)
+	b := strings.Builder{}
+	if c.binary {
+		b.WriteString("printf '%s' " + shellQuote(base64.StdEncoding.EncodeToString(c.body)) + " | base64 -d | ")
+	}
+	b.WriteString("curl -X " + shellQuote(c.method) + " " + shellQuote(c.url))
+	//Collect headers
+	for _, k := range c.names {
+		for _, v := range c.headers[k] {
+			b.WriteString(" -H " + shellQuote(k+": "+v))
+		}
+	}
+	if c.headers.Get("Accept-Encoding") != "" {
+		b.WriteString(" --compressed")
+	}
+	if c.http2 {
+		b.WriteString(" --http2")
+	}
+	// log body
+	if c.binary {
+		b.WriteString(" --data-binary @-")
+	} else if c.body != nil {
+		b.WriteString(" --data-binary " + shellQuote(string(c.body)))
+	}
+	b.WriteString(c.truncatedMarker())
+	return b.String()
+}
+
+// BuildHTTPie Build HTTPie command for logging
+// Command can be pasted into POSIX shell
+// Sensitive data is masked as BuildCURL does
+func BuildHTTPie(request HttpRequest) string {
+	c := newCommandRequest(request)
+	b := strings.Builder{}
+	if c.binary {
+		b.WriteString("printf '%s' " + shellQuote(base64.StdEncoding.EncodeToString(c.body)) + " | base64 -d | ")
+	}
+	b.WriteString("http")
+	if c.http2 {
+		b.WriteString(" --http2")
+	}
+	if c.body != nil && !c.binary {
+		b.WriteString(" --raw " + shellQuote(string(c.body)))
+	}
+	b.WriteString(" " + shellQuote(c.method) + " " + shellQuote(c.url))
+	for _, k := range c.names {
+		for _, v := range c.headers[k] {
+			b.WriteString(" " + shellQuote(k+":"+v))
+		}
+	}
+	b.WriteString(c.truncatedMarker())
+	return b.String()
+}
+
+// BuildPowerShell Build PowerShell Invoke-WebRequest command for logging
+// Sensitive data is masked as BuildCURL does
+func BuildPowerShell(request HttpRequest) string {
+	c := newCommandRequest(request)
+	b := strings.Builder{}
+	b.WriteString("Invoke-WebRequest -Method " + powerShellQuote(c.method) + " -Uri " + powerShellQuote(c.url))
+	var headers []string
+	for _, k := range c.names {
+		if strings.EqualFold(k, "Content-Type") {
+			b.WriteString(" -ContentType " + powerShellQuote(strings.Join(c.headers[k], ", ")))
+			continue
+		}
+		headers = append(headers, powerShellQuote(k)+" = "+powerShellQuote(strings.Join(c.headers[k], ", ")))
+	}
+	if len(headers) > 0 {
+		b.WriteString(" -Headers @{ " + strings.Join(headers, "; ") + " }")
+	}
+	if c.binary {
+		b.WriteString(" -Body ([Convert]::FromBase64String(" + powerShellQuote(base64.StdEncoding.EncodeToString(c.body)) + "))")
+	} else if c.body != nil {
+		b.WriteString(" -Body " + powerShellQuote(string(c.body)))
+	}
+	b.WriteString(c.truncatedMarker())
+	return b.String()
+}
+
+// Quote string for POSIX shell
+// Safe strings are not quoted
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:@%+=,") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Escape of PowerShell single quoted string
+// Typographic single quotes are also quote characters in PowerShell
+var powerShellQuoteReplacer = strings.NewReplacer("'", "''", "\u2018", "\u2018\u2018", "\u2019", "\u2019\u2019", "\u201a", "\u201a\u201a", "\u201b", "\u201b\u201b")
+
+// Quote string for PowerShell
+func powerShellQuote(s string) string {
+	return "'" + powerShellQuoteReplacer.Replace(s) + "'"
+}
+
+// Truncate body to size
+// Text body is truncated on UTF-8 character boundary
+func truncateBody(body []byte, size int, binary bool) []byte {
+	for !binary && size > 0 && !utf8.RuneStart(body[size]) {
+		size--
+	}
+	return body[:size]
+}
+
+// Check if body is binary
+// Body is binary when it is not valid UTF-8 or contains control characters
+func isBinary(body []byte) bool {
+	if !utf8.Valid(body) {
+		return true
+	}
+	for _, c := range body {
+		if (c < 0x20 && c != '\t' && c != '\n' && c != '\r') || c == 0x7f {
+			return true
+		}
+	}
+	return false
+}
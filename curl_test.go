@@ -0,0 +1,208 @@
+package goreq
+
+import (
+	"bytes"
+	"compress/gzip"
+	"golang.org/x/net/http2"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestShellQuote(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh is not available")
+	}
+	for _, s := range []string{"simple", "it's", `"double" $HOME $(id) \n`, "multi\nline", "'", ""} {
+		out, err := exec.Command(sh, "-c", "printf '%s' "+shellQuote(s)).Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != s {
+			t.Fatal("wrong quoting", s, string(out))
+		}
+	}
+}
+
+func TestBuildCURLCommand(t *testing.T) {
+	request := HttpRequest{
+		Method:  http.MethodPost,
+		Host:    "http://localhost",
+		Url:     "/posts?name=it's",
+		Headers: http.Header{"X-B": {"2"}, "X-A": {"1", "one"}, "Accept-Encoding": {"gzip"}},
+		Body:    []byte(`{"title":"it's"}`),
+		Client:  &http.Client{Transport: &http2.Transport{}},
+	}
+	curl := BuildCURL(request)
+	expected := `curl -X POST 'http://localhost/posts?name=it'\''s' -H 'Accept-Encoding: gzip' -H 'X-A: 1' -H 'X-A: one' -H 'X-B: 2' --compressed --http2 --data-binary '{"title":"it'\''s"}'`
+	if curl != expected {
+		t.Fatal("wrong curl", curl)
+	}
+
+	request = HttpRequest{Method: http.MethodPut, Url: "http://localhost/file", Body: []byte{0x00, 0xff, 0x10}}
+	curl = BuildCURL(request)
+	if curl != `printf '%s' AP8Q | base64 -d | curl -X PUT http://localhost/file --data-binary @-` {
+		t.Fatal("wrong binary curl", curl)
+	}
+
+	request = HttpRequest{Method: http.MethodPost, Url: "http://localhost", Body: []byte("привет"), LogBodySize: 3}
+	curl = BuildCURL(request)
+	if curl != `curl -X POST http://localhost --data-binary 'п' # body truncated to 2 of 12 bytes` {
+		t.Fatal("wrong truncated curl", curl)
+	}
+}
+
+func TestBuildCommands(t *testing.T) {
+	request := HttpRequest{
+		Method:  http.MethodPost,
+		Url:     "http://localhost/posts",
+		Headers: http.Header{"Content-Type": {"application/json"}, "Authorization": {"Bearer token"}},
+		Body:    []byte(`{"title":"it's"}`),
+	}
+	httpie := BuildHTTPie(request)
+	if httpie != `http --raw '{"title":"it'\''s"}' POST http://localhost/posts 'Authorization:***' Content-Type:application/json` {
+		t.Fatal("wrong httpie", httpie)
+	}
+	ps := BuildPowerShell(request)
+	if ps != `Invoke-WebRequest -Method 'POST' -Uri 'http://localhost/posts' -ContentType 'application/json' -Headers @{ 'Authorization' = '***' } -Body '{"title":"it''s"}'` {
+		t.Fatal("wrong powershell", ps)
+	}
+	if !strings.Contains(BuildPowerShell(HttpRequest{Method: http.MethodPost, Body: []byte{0}}), "FromBase64String('AA==')") {
+		t.Fatal("wrong powershell binary body")
+	}
+}
+
+func TestParseCURL(t *testing.T) {
+	command := `curl 'https://api.example.com/v1/items?x=1' \
+  -H 'Content-Type: application/json' \
+  -H "X-Quote: \"quoted\"" \
+  -u user:pass --compressed -sSL \
+  --data-raw $'{"name":"it\'s"}'`
+	request, e := ParseCURL(command)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if request.Method != http.MethodPost || request.Host != "https://api.example.com" || request.Url != "/v1/items?x=1" {
+		t.Fatal("wrong request", request.Method, request.Host, request.Url)
+	}
+	if request.Headers.Get("X-Quote") != `"quoted"` || request.Headers.Get("Authorization") != "Basic dXNlcjpwYXNz" || request.Headers.Get("Accept-Encoding") != "" {
+		t.Fatal("wrong headers", request.Headers)
+	}
+	if string(request.Body) != `{"name":"it's"}` {
+		t.Fatal("wrong body", string(request.Body))
+	}
+
+	request, e = ParseCURL(`curl -G --data-urlencode 'q=a b' -d page=2 http://localhost/search`)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if request.Method != http.MethodGet || request.Url != "/search?q=a+b&page=2" || request.Body != nil {
+		t.Fatal("wrong get request", request.Method, request.Url)
+	}
+
+	request, e = ParseCURL(`curl -F name=value -k http://localhost`)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !strings.HasPrefix(request.Headers.Get("Content-Type"), "multipart/form-data") || !strings.Contains(string(request.Body), "value") || request.Client == nil {
+		t.Fatal("wrong form request")
+	}
+
+	request, e = ParseCURL(`curl -X DELETE http://localhost/items/1 --retry 3 -o out.json`)
+	if e == nil || len(e.GetDetails()) != 2 {
+		t.Fatal("unsupported flags must be reported", e)
+	}
+	if request.Method != http.MethodDelete || request.Url != "/items/1" {
+		t.Fatal("request must be parsed")
+	}
+
+	if _, e = ParseCURL(`curl 'http://localhost`); e == nil {
+		t.Fatal("unterminated quote must be reported")
+	}
+
+	// Flag with value in combined short flags
+	request, e = ParseCURL(`curl -sXPOST http://h/x`)
+	if e != nil || request.Method != http.MethodPost || request.Host+request.Url != "http://h/x" {
+		t.Fatal("wrong combined flags", e, request.Method, request.Host+request.Url)
+	}
+	request, e = ParseCURL(`curl -sH'X: 1' -sX PUT http://h/x`)
+	if e != nil || request.Headers.Get("X") != "1" || request.Method != http.MethodPut {
+		t.Fatal("wrong combined flags", e, request.Headers, request.Method)
+	}
+}
+
+func TestParseCURLCompressed(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		writer := gzip.NewWriter(w)
+		_, _ = writer.Write([]byte(`{"name":"item"}`))
+		_ = writer.Close()
+	}))
+	defer s.Close()
+	request, e := ParseCURL(`curl --compressed ` + s.URL + `/items`)
+	if e != nil {
+		t.Fatal(e)
+	}
+	var item struct {
+		Name string `json:"name"`
+	}
+	if _, err := request.EnsureJSON(request.Method, request.Url, nil, nil, &item); err != nil || item.Name != "item" {
+		t.Fatal("compressed response must be decoded", err, item)
+	}
+}
+
+func TestParseCURLRoundTrip(t *testing.T) {
+	requests := []HttpRequest{
+		{
+			Method:  http.MethodPost,
+			Host:    "http://localhost:8080",
+			Url:     "/posts?name=it's&x=1",
+			Headers: http.Header{"Content-Type": {"application/json"}, "X-Multi": {"1", "2"}},
+			Body:    []byte("{\"title\":\"it's\",\n\"body\":\"$HOME\"}"),
+		},
+		{
+			Method:  http.MethodPut,
+			Host:    "https://localhost",
+			Url:     "/file",
+			Headers: http.Header{"Content-Type": {"application/octet-stream"}},
+			Body:    []byte{0x00, 0xff, 0x10, '\''},
+		},
+		{
+			Method: http.MethodGet,
+			Host:   "https://localhost",
+			Url:    "/",
+		},
+		{
+			Method:    http.MethodGet,
+			Host:      "https://localhost",
+			Url:       "/items?token=secret",
+			Headers:   http.Header{"Authorization": {"Bearer secret"}},
+			Redaction: &Redaction{},
+		},
+	}
+	for _, origin := range requests {
+		curl := BuildCURL(origin)
+		parsed, e := ParseCURL(curl)
+		if e != nil {
+			t.Fatal(curl, e)
+		}
+		if parsed.Method != origin.Method || parsed.Host+parsed.Url != origin.Host+origin.Url || !bytes.Equal(parsed.Body, origin.Body) {
+			t.Fatal("wrong round trip", curl, parsed.Method, parsed.Host+parsed.Url, string(parsed.Body))
+		}
+		if len(parsed.Headers) != len(origin.Headers) {
+			t.Fatal("wrong round trip headers", curl, parsed.Headers)
+		}
+		for k, v := range origin.Headers {
+			if strings.Join(parsed.Headers[k], ",") != strings.Join(v, ",") {
+				t.Fatal("wrong round trip header", k, parsed.Headers[k])
+			}
+		}
+	}
+}
@@ -0,0 +1,444 @@
+package goreq
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"github.com/dimonrus/porterr"
+	"golang.org/x/net/http2"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Word of shell command
+type shellWord struct {
+	// Word value
+	value string
+	// Word is pipe operator
+	pipe bool
+}
+
+// Flags without value which does not affect request
+var curlIgnoredFlags = map[string]bool{
+	"-s": true, "--silent": true, "-S": true, "--show-error": true, "-v": true, "--verbose": true,
+	"-i": true, "--include": true, "-L": true, "--location": true, "-f": true, "--fail": true,
+	"-#": true, "--progress-bar": true, "--no-progress-meter": true,
+}
+
+// Unsupported flags with value
+// Value is skipped to not be treated as url
+var curlUnsupportedValueFlags = map[string]bool{
+	"-o": true, "--output": true, "-m": true, "--max-time": true, "--connect-timeout": true, "--retry": true,
+	"-w": true, "--write-out": true, "-x": true, "--proxy": true, "-T": true, "--upload-file": true,
+	"-r": true, "--range": true, "-c": true, "--cookie-jar": true, "-K": true, "--config": true,
+	"-E": true, "--cert": true, "--key": true, "--cacert": true, "--resolve": true, "--connect-to": true,
+}
+
+// Short flags with value
+var curlValueFlags = map[byte]bool{
+	'X': true, 'H': true, 'd': true, 'u': true, 'F': true, 'A': true, 'e': true, 'b': true,
+	'o': true, 'm': true, 'w': true, 'x': true, 'T': true, 'r': true, 'c': true, 'K': true, 'E': true,
+}
+
+// ParseCURL Parse curl command into request
+// Supported flags: -X, -H, -d, --data, --data-ascii, --data-raw, --data-binary, --data-urlencode,
+// -u, -F, -A, -e, -b, -G, -I, -k, --compressed, --http2, --url
+// Binary body passed as base64 via stdin by BuildCURL is supported
+// Request is returned even if error contains details about unsupported flags
+func ParseCURL(command string) (HttpRequest, porterr.IError) {
+	var request HttpRequest
+	words, e := splitShellCommand(command)
+	if e != nil {
+		return request, e
+	}
+	// split pipeline
+	var stdin []byte
+	var hasStdin bool
+	var segments [][]string
+	var segment []string
+	for _, w := range words {
+		if w.pipe {
+			segments = append(segments, segment)
+			segment = nil
+			continue
+		}
+		segment = append(segment, w.value)
+	}
+	segments = append(segments, segment)
+	for _, s := range segments[:len(segments)-1] {
+		switch {
+		case len(s) == 3 && s[0] == "printf" && s[1] == "%s":
+			stdin, hasStdin = []byte(s[2]), true
+		case len(s) == 3 && s[0] == "echo" && s[1] == "-n":
+			stdin, hasStdin = []byte(s[2]), true
+		case len(s) == 2 && s[0] == "echo":
+			stdin, hasStdin = []byte(s[1]+"\n"), true
+		case len(s) == 2 && s[0] == "base64" && (s[1] == "-d" || s[1] == "--decode" || s[1] == "-D"):
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(stdin)))
+			if err != nil {
+				return request, porterr.NewF(porterr.PortErrorParser, "cURL stdin decode error: %s", err)
+			}
+			stdin = decoded
+		default:
+			return request, porterr.NewF(porterr.PortErrorParser, "cURL command pipeline is not supported: %s", strings.Join(s, " "))
+		}
+	}
+	args := segments[len(segments)-1]
+	if len(args) == 0 || args[0] != "curl" {
+		return request, porterr.New(porterr.PortErrorParser, "cURL command must start with curl")
+	}
+	args = args[1:]
+
+	e = porterr.New(porterr.PortErrorParser, "cURL command contains unsupported flags").HTTP(http.StatusBadRequest)
+	request.Headers = make(http.Header)
+	var rawURL, method string
+	var data []string
+	var form []string
+	var get, insecure, useHTTP2 bool
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		var flag, value string
+		var hasValue bool
+		switch {
+		case !strings.HasPrefix(arg, "-") || arg == "-":
+			rawURL = arg
+			continue
+		case strings.HasPrefix(arg, "--"):
+			flag = arg
+		case len(arg) > 2 && curlValueFlags[arg[1]]:
+			// value attached to short flag, e.g. -XPOST
+			flag, value, hasValue = arg[:2], arg[2:], true
+		case len(arg) > 2:
+			// combined short flags, e.g. -sSL
+			// rest of word after flag with value is the value, e.g. -sXPOST
+			var expanded []string
+			for j := 1; j < len(arg); j++ {
+				if curlValueFlags[arg[j]] {
+					expanded = append(expanded, "-"+arg[j:])
+					break
+				}
+				expanded = append(expanded, "-"+arg[j:j+1])
+			}
+			args = append(args[:i+1], append(expanded, args[i+1:]...)...)
+			continue
+		default:
+			flag = arg
+		}
+		if curlIgnoredFlags[flag] {
+			continue
+		}
+		switch flag {
+		case "-G", "--get":
+			get = true
+			continue
+		case "-I", "--head":
+			method = http.MethodHead
+			continue
+		case "-k", "--insecure":
+			insecure = true
+			continue
+		case "--compressed":
+			// transport requests and decompresses gzip when Accept-Encoding is not defined
+			continue
+		case "--http2":
+			useHTTP2 = true
+			continue
+		case "-X", "--request", "-H", "--header", "-d", "--data", "--data-ascii", "--data-raw", "--data-binary",
+			"--data-urlencode", "-u", "--user", "-F", "--form", "-A", "--user-agent", "-e", "--referer", "-b", "--cookie", "--url":
+		default:
+			e = e.PushDetail(porterr.PortErrorParam, flag, "Flag is not supported")
+			if curlUnsupportedValueFlags[flag] && !hasValue {
+				i++
+			}
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				e = e.PushDetail(porterr.PortErrorParam, flag, "Flag value is not defined")
+				break
+			}
+			i++
+			value = args[i]
+		}
+		switch flag {
+		case "-X", "--request":
+			method = value
+		case "-H", "--header":
+			name, headerValue, found := strings.Cut(value, ":")
+			if !found {
+				e = e.PushDetail(porterr.PortErrorParam, flag, "Header format is not supported: "+value)
+				continue
+			}
+			request.Headers.Add(strings.TrimSpace(name), strings.TrimSpace(headerValue))
+		case "-d", "--data", "--data-ascii", "--data-binary":
+			if strings.HasPrefix(value, "@") {
+				if value != "@-" || !hasStdin {
+					e = e.PushDetail(porterr.PortErrorParam, flag, "File reference is not supported: "+value)
+					continue
+				}
+				value = string(stdin)
+				if flag != "--data-binary" {
+					value = strings.NewReplacer("\r", "", "\n", "").Replace(value)
+				}
+			}
+			data = append(data, value)
+		case "--data-raw":
+			data = append(data, value)
+		case "--data-urlencode":
+			name, content, found := strings.Cut(value, "=")
+			if strings.Contains(name, "@") || (!found && strings.HasPrefix(value, "@")) {
+				e = e.PushDetail(porterr.PortErrorParam, flag, "File reference is not supported: "+value)
+				continue
+			}
+			switch {
+			case !found:
+				data = append(data, url.QueryEscape(value))
+			case name == "":
+				data = append(data, url.QueryEscape(content))
+			default:
+				data = append(data, name+"="+url.QueryEscape(content))
+			}
+		case "-u", "--user":
+			request.Headers.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(value)))
+		case "-F", "--form":
+			name, content, found := strings.Cut(value, "=")
+			if !found || strings.HasPrefix(content, "@") || strings.HasPrefix(content, "<") {
+				e = e.PushDetail(porterr.PortErrorParam, flag, "Form field format is not supported: "+name)
+				continue
+			}
+			form = append(form, name, content)
+		case "-A", "--user-agent":
+			request.Headers.Set("User-Agent", value)
+		case "-e", "--referer":
+			request.Headers.Set("Referer", value)
+		case "-b", "--cookie":
+			if !strings.Contains(value, "=") {
+				e = e.PushDetail(porterr.PortErrorParam, flag, "Cookie file is not supported: "+value)
+				continue
+			}
+			request.Headers.Add("Cookie", value)
+		case "--url":
+			rawURL = value
+		}
+	}
+
+	// body
+	switch {
+	case len(form) > 0:
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		for i := 0; i < len(form); i += 2 {
+			part, _ := writer.CreatePart(textproto.MIMEHeader{"Content-Disposition": {`form-data; name="` + form[i] + `"`}})
+			_, _ = part.Write([]byte(form[i+1]))
+		}
+		_ = writer.Close()
+		request.Body = body.Bytes()
+		request.Headers.Set("Content-Type", writer.FormDataContentType())
+		if method == "" {
+			method = http.MethodPost
+		}
+	case len(data) > 0 && get:
+		if strings.Contains(rawURL, "?") {
+			rawURL += "&" + strings.Join(data, "&")
+		} else {
+			rawURL += "?" + strings.Join(data, "&")
+		}
+	case len(data) > 0:
+		request.Body = []byte(strings.Join(data, "&"))
+		if request.Headers.Get("Content-Type") == "" {
+			request.Headers.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		if method == "" {
+			method = http.MethodPost
+		}
+	}
+	if get && method == "" {
+		method = http.MethodGet
+	}
+	if method == "" {
+		method = http.MethodGet
+	}
+	request.Method = method
+	if len(request.Headers) == 0 {
+		request.Headers = nil
+	}
+
+	// url
+	if rawURL == "" {
+		e = e.PushDetail(porterr.PortErrorParam, "url", "Url is not defined")
+	} else {
+		if !strings.Contains(rawURL, "://") {
+			rawURL = "http://" + rawURL
+		}
+		scheme, rest, _ := strings.Cut(rawURL, "://")
+		host, path := rest, "/"
+		if index := strings.IndexAny(rest, "/?#"); index >= 0 {
+			host, path = rest[:index], rest[index:]
+			if !strings.HasPrefix(path, "/") {
+				path = "/" + path
+			}
+		}
+		request.Host = scheme + "://" + host
+		request.Url = path
+	}
+
+	// client
+	if insecure || useHTTP2 {
+		var tlsConfig *tls.Config
+		if insecure {
+			tlsConfig = &tls.Config{InsecureSkipVerify: true}
+		}
+		request.Client = &http.Client{Timeout: time.Second * DefaultTimeout}
+		if useHTTP2 {
+			request.Client.Transport = &http2.Transport{TLSClientConfig: tlsConfig}
+		} else {
+			request.Client.Transport = &http.Transport{TLSClientConfig: tlsConfig, Proxy: http.ProxyFromEnvironment}
+		}
+	}
+	return request, e.IfDetails()
+}
+
+// Split shell command into words
+// Supports quotes, escapes, line continuation, comments and pipes
+func splitShellCommand(command string) ([]shellWord, porterr.IError) {
+	var words []shellWord
+	var b strings.Builder
+	var inWord bool
+	runes := []rune(command)
+	flush := func() {
+		if inWord {
+			words = append(words, shellWord{value: b.String()})
+			b.Reset()
+			inWord = false
+		}
+	}
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case c == '\\':
+			if i+1 < len(runes) {
+				i++
+				if runes[i] != '\n' {
+					b.WriteRune(runes[i])
+					inWord = true
+				}
+			}
+		case c == '\'':
+			end := indexRune(runes, i+1, '\'')
+			if end < 0 {
+				return nil, porterr.New(porterr.PortErrorParser, "cURL command has unterminated single quote")
+			}
+			b.WriteString(string(runes[i+1 : end]))
+			i = end
+			inWord = true
+		case c == '$' && i+1 < len(runes) && runes[i+1] == '\'':
+			end, e := unquoteANSI(runes, i+2, &b)
+			if e != nil {
+				return nil, e
+			}
+			i = end
+			inWord = true
+		case c == '"':
+			j := i + 1
+			for ; j < len(runes) && runes[j] != '"'; j++ {
+				if runes[j] == '\\' && j+1 < len(runes) && strings.ContainsRune("$`\"\\\n", runes[j+1]) {
+					j++
+					if runes[j] == '\n' {
+						continue
+					}
+				}
+				b.WriteRune(runes[j])
+			}
+			if j >= len(runes) {
+				return nil, porterr.New(porterr.PortErrorParser, "cURL command has unterminated double quote")
+			}
+			i = j
+			inWord = true
+		case c == '|':
+			flush()
+			words = append(words, shellWord{pipe: true})
+		case c == '#' && !inWord:
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case unicode.IsSpace(c):
+			flush()
+		default:
+			b.WriteRune(c)
+			inWord = true
+		}
+	}
+	flush()
+	return words, nil
+}
+
+// Unquote ANSI-C string $'...' starting from position
+// Returns position of closing quote
+func unquoteANSI(runes []rune, start int, b *strings.Builder) (int, porterr.IError) {
+	for i := start; i < len(runes); i++ {
+		c := runes[i]
+		if c == '\'' {
+			return i, nil
+		}
+		if c != '\\' || i+1 >= len(runes) {
+			b.WriteRune(c)
+			continue
+		}
+		i++
+		switch runes[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case 'a':
+			b.WriteByte('\a')
+		case 'b':
+			b.WriteByte('\b')
+		case 'e', 'E':
+			b.WriteByte(0x1b)
+		case 'f':
+			b.WriteByte('\f')
+		case 'v':
+			b.WriteByte('\v')
+		case 'x', 'u', 'U':
+			size := map[rune]int{'x': 2, 'u': 4, 'U': 8}[runes[i]]
+			end := i + 1
+			for end < len(runes) && end < i+1+size && strings.ContainsRune("0123456789abcdefABCDEF", runes[end]) {
+				end++
+			}
+			code, err := strconv.ParseUint(string(runes[i+1:end]), 16, 32)
+			if err != nil {
+				b.WriteRune('\\')
+				b.WriteRune(runes[i])
+				continue
+			}
+			if runes[i] == 'x' {
+				b.WriteByte(byte(code))
+			} else {
+				b.WriteRune(rune(code))
+			}
+			i = end - 1
+		default:
+			b.WriteRune(runes[i])
+		}
+	}
+	return 0, porterr.New(porterr.PortErrorParser, "cURL command has unterminated ANSI-C quote")
+}
+
+// Index of rune starting from position
+func indexRune(runes []rune, start int, r rune) int {
+	for i := start; i < len(runes); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
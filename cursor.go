@@ -0,0 +1,170 @@
+package goreq
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/dimonrus/porterr"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// CursorExtractor Extract items and next cursor from response
+// Empty cursor means the last page
+type CursorExtractor[R any] func(response *http.Response, body []byte) (items []R, cursor string, err error)
+
+// CursorInjector Place cursor into request of the next page
+type CursorInjector func(request *HttpRequest, cursor string) error
+
+// CursorPaginator Sequential cursor pagination
+type CursorPaginator[R any] struct {
+	// Extract items and next cursor from page response
+	Extract CursorExtractor[R]
+	// Place cursor into next page request
+	Inject CursorInjector
+	// Cursor of the first page. Empty - first page is requested without cursor
+	Cursor string
+	// Max count of requested pages. 0 - unlimited
+	MaxPages int
+}
+
+// CursorPaginatorEnsure Request pages one by one until cursor is empty or max pages reached
+// Returns collected items and cursor of the next page which is empty when all pages were fetched
+func CursorPaginatorEnsure[R any](ctx context.Context, hr HttpRequest, paginator CursorPaginator[R]) (items []R, cursor string, e porterr.IError) {
+	if paginator.Extract == nil || paginator.Inject == nil {
+		e = porterr.New(porterr.PortErrorRequest, "cursor paginator must have extractor and injector")
+		return
+	}
+	cursor = paginator.Cursor
+	seen := make(map[string]struct{})
+	for page := 0; paginator.MaxPages == 0 || page < paginator.MaxPages; page++ {
+		var data []R
+		data, cursor, e = paginator.fetch(ctx, hr, cursor)
+		if e != nil {
+			return
+		}
+		items = append(items, data...)
+		if cursor == "" {
+			return
+		}
+		if _, ok := seen[cursor]; ok {
+			e = porterr.NewF(porterr.PortErrorResponse, "Http Request (%s) cursor %s repeats. Service: %s", hr.Url, cursor, hr.Label)
+			return
+		}
+		seen[cursor] = struct{}{}
+	}
+	return
+}
+
+// Fetch page with cursor
+func (p CursorPaginator[R]) fetch(ctx context.Context, hr HttpRequest, cursor string) (items []R, next string, e porterr.IError) {
+	req := hr
+	if cursor != "" {
+		if err := p.Inject(&req, cursor); err != nil {
+			e = porterr.NewF(porterr.PortErrorRequest, "Http Request (%s) cursor inject error: %s. Service: %s", hr.Url, err, hr.Label)
+			return
+		}
+	}
+	response, body, err := EnsureContext(ctx, req)
+	if err != nil {
+		e = asPortError(err)
+		return
+	}
+	items, next, err = p.Extract(response, body)
+	if err != nil {
+		e = porterr.NewF(porterr.PortErrorBody, "Http Response (%s) cursor extract error: %s. Service: %s", req.Host+req.Url, err, req.Label)
+	}
+	return
+}
+
+// CursorQuery Injector which sets cursor into query parameter
+func CursorQuery(name string) CursorInjector {
+	return func(request *HttpRequest, cursor string) error {
+		u, err := url.Parse(request.Url)
+		if err != nil {
+			return err
+		}
+		query := u.Query()
+		query.Set(name, cursor)
+		u.RawQuery = query.Encode()
+		request.Url = u.String()
+		return nil
+	}
+}
+
+// CursorHeader Injector which sets cursor into header
+func CursorHeader(name string) CursorInjector {
+	return func(request *HttpRequest, cursor string) error {
+		request.Headers = request.Headers.Clone()
+		if request.Headers == nil {
+			request.Headers = make(http.Header)
+		}
+		request.Headers.Set(name, cursor)
+		return nil
+	}
+}
+
+// CursorBodyField Injector which sets cursor into JSON body field
+// Path segments are separated by dot, missing objects are created
+func CursorBodyField(path string) CursorInjector {
+	return func(request *HttpRequest, cursor string) error {
+		body := map[string]interface{}{}
+		if len(bytes.TrimSpace(request.Body)) > 0 {
+			decoder := json.NewDecoder(bytes.NewReader(request.Body))
+			decoder.UseNumber()
+			if err := decoder.Decode(&body); err != nil {
+				return err
+			}
+		}
+		object := body
+		segments := strings.Split(path, ".")
+		for _, segment := range segments[:len(segments)-1] {
+			next, ok := object[segment].(map[string]interface{})
+			if !ok {
+				next = map[string]interface{}{}
+				object[segment] = next
+			}
+			object = next
+		}
+		object[segments[len(segments)-1]] = cursor
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		request.Body = data
+		return nil
+	}
+}
+
+// JSONCursor Extractor of items and cursor from JSON body
+// Paths are separated by dot, e.g. data.items and meta.next_cursor
+func JSONCursor[R any](itemsPath string, cursorPath string) CursorExtractor[R] {
+	return func(response *http.Response, body []byte) (items []R, cursor string, err error) {
+		var data interface{}
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		if err = decoder.Decode(&data); err != nil {
+			return
+		}
+		if value := jsonLookup(data, itemsPath); value != nil {
+			var raw []byte
+			if raw, err = json.Marshal(value); err != nil {
+				return
+			}
+			if err = json.Unmarshal(raw, &items); err != nil {
+				return
+			}
+		}
+		cursor = jsonString(jsonLookup(data, cursorPath))
+		return
+	}
+}
+
+// Convert error into porterr
+func asPortError(err error) porterr.IError {
+	if e, ok := err.(porterr.IError); ok {
+		return e
+	}
+	return porterr.New(porterr.PortErrorSystem, err.Error())
+}
@@ -0,0 +1,82 @@
+package goreq
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestCursorPaginatorEnsure(t *testing.T) {
+	// Cursor is the index of the first item of page
+	items := []int{1, 2, 3, 4, 5, 6, 7}
+	page := func(cursor string) []byte {
+		start, _ := strconv.Atoi(cursor)
+		end := min(start+3, len(items))
+		next := ""
+		if end < len(items) {
+			next = strconv.Itoa(end)
+		}
+		data, _ := json.Marshal(map[string]interface{}{"data": items[start:end], "meta": map[string]string{"next_cursor": next}})
+		return data
+	}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/query":
+			_, _ = w.Write(page(r.URL.Query().Get("after")))
+		case "/header":
+			_, _ = w.Write(page(r.Header.Get("X-Cursor")))
+		case "/body":
+			var body struct {
+				Page struct {
+					Cursor string `json:"cursor"`
+				} `json:"page"`
+				Filter string `json:"filter"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if body.Filter != "all" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write(page(body.Page.Cursor))
+		case "/loop":
+			_, _ = w.Write([]byte(`{"data":[1],"meta":{"next_cursor":"same"}}`))
+		}
+	}))
+	defer s.Close()
+	extract := JSONCursor[int]("data", "meta.next_cursor")
+	cases := []struct {
+		url    string
+		body   []byte
+		inject CursorInjector
+	}{
+		{"/query?limit=3", nil, CursorQuery("after")},
+		{"/header", nil, CursorHeader("X-Cursor")},
+		{"/body", []byte(`{"filter":"all"}`), CursorBodyField("page.cursor")},
+	}
+	for _, c := range cases {
+		hr := HttpRequest{Method: http.MethodPost, Host: s.URL, Url: c.url, Body: c.body}
+		result, cursor, e := CursorPaginatorEnsure(context.Background(), hr, CursorPaginator[int]{Extract: extract, Inject: c.inject})
+		if e != nil {
+			t.Fatal(c.url, e)
+		}
+		if len(result) != len(items) || result[6] != 7 || cursor != "" {
+			t.Fatal("wrong items", c.url, result, cursor)
+		}
+	}
+	hr := HttpRequest{Method: http.MethodGet, Host: s.URL, Url: "/query"}
+	result, cursor, e := CursorPaginatorEnsure(context.Background(), hr, CursorPaginator[int]{Extract: extract, Inject: CursorQuery("after"), MaxPages: 2})
+	if e != nil || len(result) != 6 || cursor != "6" {
+		t.Fatal("max pages must stop pagination", result, cursor, e)
+	}
+	result, cursor, e = CursorPaginatorEnsure(context.Background(), hr, CursorPaginator[int]{Extract: extract, Inject: CursorQuery("after"), Cursor: cursor})
+	if e != nil || len(result) != 1 || cursor != "" {
+		t.Fatal("pagination must continue from cursor", result, cursor, e)
+	}
+	hr.Url = "/loop"
+	if _, _, e = CursorPaginatorEnsure(context.Background(), hr, CursorPaginator[int]{Extract: extract, Inject: CursorQuery("after")}); e == nil {
+		t.Fatal("repeated cursor must be an error")
+	}
+}
@@ -0,0 +1,220 @@
+package goreq
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/dimonrus/porterr"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// ProblemContentType Content type of RFC 7807 problem details
+const ProblemContentType = "application/problem+json"
+
+// ErrorDecoder Response error strategy which decodes upstream error body into porterr
+// Supported bodies: gorest error response, RFC 7807 problem details and generic shapes
+// Body which can not be decoded produces default response error
+type ErrorDecoder struct {
+	// Generic error body shapes. Checked in order after gorest and problem details
+	Shapes []ErrorShape
+}
+
+// ErrorShape Generic JSON error body shape
+// Fields are paths separated by dot, e.g. error.message
+type ErrorShape struct {
+	// Path to error code. PortErrorResponse if not found
+	Code string
+	// Path to error message. Required
+	Message string
+	// Path to array of error details
+	Details string
+	// Detail code field. PortErrorParam if not found
+	DetailCode string
+	// Detail name field
+	DetailName string
+	// Detail message field
+	DetailMessage string
+}
+
+// DecodeResponseError Response error strategy which decodes gorest and problem details error bodies
+func DecodeResponseError(response *http.Response) error {
+	return ErrorDecoder{}.Decode(response)
+}
+
+// Decode Response error strategy
+// Error has code, message and details of upstream error and response status as HTTP code
+func (d ErrorDecoder) Decode(response *http.Response) error {
+	if response.StatusCode < http.StatusBadRequest {
+		return nil
+	}
+	body, err := ResponseBody(response)
+	if err != nil || len(bytes.TrimSpace(body)) == 0 {
+		return responseError(response)
+	}
+	var data interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if decoder.Decode(&data) != nil {
+		return responseError(response)
+	}
+	var e porterr.IError
+	if mediaType, _, _ := mime.ParseMediaType(response.Header.Get("Content-Type")); mediaType == ProblemContentType {
+		e = problemError(data)
+	}
+	if e == nil {
+		e = gorestError(data)
+	}
+	for i := 0; e == nil && i < len(d.Shapes); i++ {
+		e = d.Shapes[i].decode(data)
+	}
+	if e == nil {
+		return responseError(response)
+	}
+	return e.HTTP(response.StatusCode)
+}
+
+// Error of gorest response
+// {"error": {"code": "", "name": "", "message": "", "data": [{"code": "", "name": "", "message": ""}]}}
+func gorestError(data interface{}) porterr.IError {
+	object, ok := jsonLookup(data, "error").(map[string]interface{})
+	if !ok || object["code"] == nil {
+		return nil
+	}
+	e := porterr.NewWithName(jsonCode(object["code"]), jsonString(object["name"]), jsonString(object["message"]))
+	details, _ := object["data"].([]interface{})
+	for _, item := range details {
+		if detail, ok := item.(map[string]interface{}); ok {
+			e = e.PushDetail(jsonCode(detail["code"]), jsonString(detail["name"]), jsonString(detail["message"]))
+		}
+	}
+	return e
+}
+
+// Error of RFC 7807 problem details
+// Type is the code, detail or title is the message
+// Members of errors and invalid-params arrays are details
+func problemError(data interface{}) porterr.IError {
+	object, ok := data.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	code := jsonString(object["type"])
+	if code == "" || code == "about:blank" {
+		code = porterr.PortErrorResponse
+	}
+	message := jsonString(object["detail"])
+	if message == "" {
+		message = jsonString(object["title"])
+	}
+	e := porterr.NewWithName(code, jsonString(object["instance"]), message)
+	for _, field := range []string{"errors", "invalid-params"} {
+		details, _ := object[field].([]interface{})
+		for _, item := range details {
+			detail, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			var detailCode interface{} = porterr.PortErrorParam
+			if detail["code"] != nil {
+				detailCode = jsonCode(detail["code"])
+			}
+			name := jsonString(detail["name"])
+			if name == "" {
+				name = jsonString(detail["pointer"])
+			}
+			detailMessage := jsonString(detail["reason"])
+			if detailMessage == "" {
+				detailMessage = jsonString(detail["detail"])
+			}
+			if detailMessage == "" {
+				detailMessage = jsonString(detail["message"])
+			}
+			e = e.PushDetail(detailCode, name, detailMessage)
+		}
+	}
+	return e
+}
+
+// Decode error of shape
+// Returns nil if message is not found
+func (s ErrorShape) decode(data interface{}) porterr.IError {
+	message := jsonLookup(data, s.Message)
+	if s.Message == "" || message == nil {
+		return nil
+	}
+	var code interface{} = porterr.PortErrorResponse
+	if s.Code != "" {
+		if c := jsonLookup(data, s.Code); c != nil {
+			code = jsonCode(c)
+		}
+	}
+	e := porterr.New(code, jsonString(message))
+	if s.Details == "" {
+		return e
+	}
+	details, _ := jsonLookup(data, s.Details).([]interface{})
+	for _, item := range details {
+		var detailCode interface{} = porterr.PortErrorParam
+		if s.DetailCode != "" {
+			if c := jsonLookup(item, s.DetailCode); c != nil {
+				detailCode = jsonCode(c)
+			}
+		}
+		var name, detailMessage string
+		if s.DetailName != "" {
+			name = jsonString(jsonLookup(item, s.DetailName))
+		}
+		if s.DetailMessage != "" {
+			detailMessage = jsonString(jsonLookup(item, s.DetailMessage))
+		} else if text, ok := item.(string); ok {
+			detailMessage = text
+		}
+		e = e.PushDetail(detailCode, name, detailMessage)
+	}
+	return e
+}
+
+// Get JSON value by path separated by dot
+func jsonLookup(data interface{}, path string) interface{} {
+	if path == "" {
+		return data
+	}
+	for _, segment := range strings.Split(path, ".") {
+		object, ok := data.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		data = object[segment]
+	}
+	return data
+}
+
+// JSON value as string
+func jsonString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+// JSON value as error code
+// Integer codes are kept as int
+func jsonCode(value interface{}) interface{} {
+	if number, ok := value.(json.Number); ok {
+		if i, err := number.Int64(); err == nil {
+			return int(i)
+		}
+	}
+	if _, ok := value.(string); ok {
+		return value
+	}
+	return jsonString(value)
+}
@@ -0,0 +1,70 @@
+package goreq
+
+import (
+	"github.com/dimonrus/porterr"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestErrorDecoder(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gorest":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"code":"PORTABLE_ERROR_VALIDATION","message":"Request is invalid","data":[{"code":"PORTABLE_ERROR_PARAM","name":"name","message":"Name is required"}]}}`))
+		case "/problem":
+			w.Header().Set("Content-Type", ProblemContentType)
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_, _ = w.Write([]byte(`{"type":"https://example.com/probs/out-of-credit","title":"You do not have enough credit.","detail":"Your current balance is 30","instance":"/account/1","invalid-params":[{"name":"age","reason":"must be a positive integer"}]}`))
+		case "/generic":
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`{"status":{"code":409,"text":"Item exists"},"errors":[{"field":"id","msg":"duplicate"}]}`))
+		case "/ok":
+			_, _ = w.Write([]byte(`{"error":{"code":"IGNORED"}}`))
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte(`<html>Bad Gateway</html>`))
+		}
+	}))
+	defer s.Close()
+	decoder := ErrorDecoder{Shapes: []ErrorShape{{Code: "status.code", Message: "status.text", Details: "errors", DetailName: "field", DetailMessage: "msg"}}}
+	req := HttpRequest{Method: http.MethodGet, Host: s.URL, ResponseErrorStrategy: decoder.Decode}
+	cases := []struct {
+		url     string
+		code    interface{}
+		message string
+		http    int
+		details []porterr.ErrorData
+	}{
+		{"/gorest", porterr.PortErrorValidation, "Request is invalid", http.StatusBadRequest, []porterr.ErrorData{{Code: porterr.PortErrorParam, Name: "name", Message: "Name is required"}}},
+		{"/problem", "https://example.com/probs/out-of-credit", "Your current balance is 30", http.StatusUnprocessableEntity, []porterr.ErrorData{{Code: porterr.PortErrorParam, Name: "age", Message: "must be a positive integer"}}},
+		{"/generic", 409, "Item exists", http.StatusConflict, []porterr.ErrorData{{Code: porterr.PortErrorParam, Name: "id", Message: "duplicate"}}},
+		{"/html", porterr.PortErrorResponse, "Bad Gateway: /html Service: " + s.Listener.Addr().String(), http.StatusBadGateway, nil},
+	}
+	for _, c := range cases {
+		req.Url = c.url
+		_, body, err := Ensure(req)
+		e, ok := err.(porterr.IError)
+		if !ok {
+			t.Fatal("must be porterr", c.url, err)
+		}
+		if e.GetCode() != c.code || e.Origin().Message != c.message || e.GetHTTP() != c.http || len(body) == 0 {
+			t.Fatal("wrong error", c.url, e.GetCode(), e.Origin().Message, e.GetHTTP())
+		}
+		details := e.GetDetails()
+		if len(details) != len(c.details) {
+			t.Fatal("wrong details", c.url, details)
+		}
+		for i := range details {
+			if details[i].Origin().ErrorData != c.details[i] {
+				t.Fatal("wrong detail", c.url, details[i].Origin().ErrorData)
+			}
+		}
+	}
+	req.Url = "/ok"
+	req.ResponseErrorStrategy = DecodeResponseError
+	if _, _, err := Ensure(req); err != nil {
+		t.Fatal("successful response is not an error", err)
+	}
+}
@@ -0,0 +1,125 @@
+package goreq
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/dimonrus/porterr"
+	"time"
+)
+
+// AttemptResult Result of performed attempt
+type AttemptResult struct {
+	// Number of attempt starting from 0
+	Attempt uint
+	// Response status code. 0 if server does not respond
+	Status int
+	// Transport error
+	Error error
+	// Duration of attempt
+	Duration time.Duration
+}
+
+// RequestError Error of request with history of attempts
+// Implements porterr.IError and marshals to JSON as origin error
+// Use Origin() or errors.As to get *porterr.PortError
+type RequestError struct {
+	porterr.IError
+	// Performed attempts
+	Attempts []AttemptResult `json:"-"`
+	// Last attempt could be retried but retry count or retry budget was exhausted
+	RetriesExhausted bool `json:"-"`
+}
+
+// Create request error with attempts of request
+// Returns origin error if it is not porterr
+func newRequestError(request *HttpRequest, err error) error {
+	e, ok := err.(porterr.IError)
+	if !ok {
+		return err
+	}
+	if re, ok := e.(*RequestError); ok {
+		e = re.IError
+	}
+	return &RequestError{IError: e, Attempts: request.attempts, RetriesExhausted: request.exhausted}
+}
+
+// Unwrap Get origin error
+func (e *RequestError) Unwrap() error {
+	return e.IError
+}
+
+// MarshalJSON Marshal origin error
+// Attempts are not marshaled
+func (e *RequestError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.IError)
+}
+
+// Code Set error code
+func (e *RequestError) Code(code interface{}) porterr.IError {
+	e.IError = e.IError.Code(code)
+	return e
+}
+
+// FlushDetails Reset all details
+func (e *RequestError) FlushDetails() porterr.IError {
+	e.IError = e.IError.FlushDetails()
+	return e
+}
+
+// HTTP Set HTTP code
+func (e *RequestError) HTTP(httpCode int) porterr.IError {
+	e.IError = e.IError.HTTP(httpCode)
+	return e
+}
+
+// IfDetails Return error if error has details else nil
+func (e *RequestError) IfDetails() porterr.IError {
+	if e.IError.IfDetails() == nil {
+		return nil
+	}
+	return e
+}
+
+// MergeDetails Merge detail to error
+func (e *RequestError) MergeDetails(errs ...porterr.IError) porterr.IError {
+	e.IError = e.IError.MergeDetails(errs...)
+	return e
+}
+
+// AsDetails Append to details list of errors
+func (e *RequestError) AsDetails(errs ...porterr.IError) porterr.IError {
+	e.IError = e.IError.AsDetails(errs...)
+	return e
+}
+
+// PushDetail Add error detail
+func (e *RequestError) PushDetail(code interface{}, name string, message string) porterr.IError {
+	e.IError = e.IError.PushDetail(code, name, message)
+	return e
+}
+
+// IsTimeout Check if request failed with attempt or total timeout
+func IsTimeout(err error) bool {
+	return errorCode(err) == PortErrorTimeout
+}
+
+// IsCircuitOpen Check if request was rejected by circuit breaker
+func IsCircuitOpen(err error) bool {
+	return errorCode(err) == PortErrorCircuitOpen
+}
+
+// IsRetryExhausted Check if request failed when last attempt could be retried
+// but retry count or retry budget was exhausted
+func IsRetryExhausted(err error) bool {
+	var e *RequestError
+	return errors.As(err, &e) && e.RetriesExhausted
+}
+
+// Code of porterr error
+func errorCode(err error) interface{} {
+	var e porterr.IError
+	if errors.As(err, &e) {
+		return e.GetCode()
+	}
+	return nil
+}
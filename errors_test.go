@@ -0,0 +1,81 @@
+package goreq
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/dimonrus/porterr"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRequestError(t *testing.T) {
+	var calls int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		switch r.URL.Path {
+		case "/unavailable":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/slow":
+			time.Sleep(time.Millisecond * 50)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer s.Close()
+	req := HttpRequest{Method: http.MethodGet, Host: s.URL, Url: "/unavailable", RetryCount: 2}
+	_, _, err := Ensure(req)
+	var e *RequestError
+	if !errors.As(err, &e) || !IsRetryExhausted(err) || IsTimeout(err) || IsCircuitOpen(err) {
+		t.Fatal("retries must be exhausted", err)
+	}
+	if len(e.Attempts) != 3 || e.Attempts[2].Attempt != 2 || e.Attempts[2].Status != http.StatusServiceUnavailable || e.Attempts[2].Duration <= 0 {
+		t.Fatal("wrong attempts", e.Attempts)
+	}
+	if e.GetHTTP() != http.StatusServiceUnavailable || e.GetCode() != porterr.PortErrorResponse {
+		t.Fatal("origin error must be kept", e)
+	}
+	if pe, ok := e.PushDetail(porterr.PortErrorParam, "name", "message").(*RequestError); !ok || len(pe.GetDetails()) != 1 {
+		t.Fatal("request error must be kept after detail push")
+	}
+	// Wire format of origin error
+	data, _ := json.Marshal(err)
+	origin, _ := json.Marshal(e.Origin())
+	if string(data) != string(origin) {
+		t.Fatal("request error must be marshaled as origin error", string(data))
+	}
+	if d := e.PopDetail(); d == nil || d.Origin().Name != "name" || len(e.GetDetails()) != 0 || e.PopDetail() != nil {
+		t.Fatal("detail must be removed from origin error", d)
+	}
+	// Not retryable status
+	req.Url = "/missing"
+	_, _, err = Ensure(req)
+	if !errors.As(err, &e) || IsRetryExhausted(err) || len(e.Attempts) != 1 {
+		t.Fatal("not retryable status must not exhaust retries", err)
+	}
+	// Transport error
+	closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closed.Close()
+	_, _, err = Ensure(HttpRequest{Method: http.MethodGet, Url: closed.URL, RetryCount: 1})
+	if !errors.As(err, &e) || !IsRetryExhausted(err) || len(e.Attempts) != 2 || e.Attempts[1].Error == nil || e.Attempts[1].Status != 0 {
+		t.Fatal("transport error must exhaust retries", err)
+	}
+	// Timeout
+	req.Url = "/slow"
+	req.AttemptTimeout = time.Millisecond * 10
+	req.RetryCount = 0
+	_, _, err = Ensure(req)
+	if !IsTimeout(err) || IsRetryExhausted(err) {
+		t.Fatal("must be timeout", err)
+	}
+	// Circuit open
+	breaker := &CircuitBreaker{MinRequests: 1, OpenDuration: time.Minute}
+	req = HttpRequest{Method: http.MethodGet, Host: s.URL, Url: "/unavailable", CircuitBreaker: breaker}
+	_, _, _ = Ensure(req)
+	_, _, err = Ensure(req)
+	if !IsCircuitOpen(err) {
+		t.Fatal("circuit must be open", err)
+	}
+}
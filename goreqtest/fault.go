@@ -0,0 +1,275 @@
+package goreqtest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// FaultKind Kind of injected fault
+type FaultKind int
+
+const (
+	// FaultLatency Delay request before it is sent
+	FaultLatency FaultKind = iota
+	// FaultStatus Respond with status code without sending request
+	FaultStatus
+	// FaultReset Fail request with connection reset error
+	FaultReset
+	// FaultTruncate Fail response body read after some bytes
+	FaultTruncate
+	// FaultTimeout Hang until request context is done or timeout error after latency
+	FaultTimeout
+)
+
+// String Name of fault kind
+func (k FaultKind) String() string {
+	switch k {
+	case FaultLatency:
+		return "latency"
+	case FaultStatus:
+		return "status"
+	case FaultReset:
+		return "reset"
+	case FaultTruncate:
+		return "truncate"
+	case FaultTimeout:
+		return "timeout"
+	default:
+		return "unknown"
+	}
+}
+
+// FaultRule Rule of fault injection
+type FaultRule struct {
+	// Kind of fault
+	Kind FaultKind
+	// Request host. Empty matches any host
+	Host string
+	// Request path pattern in path.Match syntax. Empty matches any path
+	Path string
+	// Request method. Empty matches any method
+	Method string
+	// Probability of injection from 0 to 1. 0 - always
+	Probability float64
+	// Max count of injections. 0 - unlimited
+	Times int
+	// Delay of FaultLatency and FaultTimeout
+	Latency time.Duration
+	// Status code of FaultStatus. 503 if not defined
+	Status int
+	// Headers of FaultStatus response, e.g. Retry-After
+	Header http.Header
+	// Count of body bytes read before FaultTruncate error
+	TruncateAfter int
+}
+
+// FaultTransport Fault injecting http.RoundTripper
+// Rules are checked in order, latency faults are accumulated, first other fault is injected
+type FaultTransport struct {
+	// Next transport. http.DefaultTransport if not defined
+	Next http.RoundTripper
+	// Fault rules
+	Rules []FaultRule
+
+	mu sync.Mutex
+	// Seeded random
+	random *rand.Rand
+	// Count of injections per rule
+	injections []int
+	// Count of injected faults per kind
+	counters map[FaultKind]int
+}
+
+// NewFaultTransport Create fault transport with seed for reproducible runs
+func NewFaultTransport(seed uint64, next http.RoundTripper, rules ...FaultRule) *FaultTransport {
+	return &FaultTransport{
+		Next:   next,
+		Rules:  rules,
+		random: rand.New(rand.NewPCG(seed, seed)),
+	}
+}
+
+// Client Http client with fault transport
+func (t *FaultTransport) Client() *http.Client {
+	return &http.Client{Transport: t}
+}
+
+// Injected Count of injected faults of kind
+func (t *FaultTransport) Injected(kind FaultKind) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.counters[kind]
+}
+
+// Counters Count of injected faults per kind
+func (t *FaultTransport) Counters() map[FaultKind]int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	counters := make(map[FaultKind]int, len(t.counters))
+	for k, v := range t.counters {
+		counters[k] = v
+	}
+	return counters
+}
+
+// RoundTrip Perform request with injected faults
+func (t *FaultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	latency, fault := t.faults(req)
+	if latency > 0 {
+		if err := wait(req.Context(), latency); err != nil {
+			closeBody(req)
+			return nil, err
+		}
+	}
+	if fault != nil {
+		switch fault.Kind {
+		case FaultStatus, FaultReset, FaultTimeout:
+			// request is not passed to next transport
+			closeBody(req)
+		}
+		switch fault.Kind {
+		case FaultStatus:
+			status := fault.Status
+			if status == 0 {
+				status = http.StatusServiceUnavailable
+			}
+			header := fault.Header.Clone()
+			if header == nil {
+				header = make(http.Header)
+			}
+			return &http.Response{
+				Status:     strconv.Itoa(status) + " " + http.StatusText(status),
+				StatusCode: status,
+				Proto:      "HTTP/1.1",
+				ProtoMajor: 1,
+				ProtoMinor: 1,
+				Header:     header,
+				Body:       io.NopCloser(bytes.NewReader(nil)),
+				Request:    req,
+			}, nil
+		case FaultReset:
+			return nil, &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}
+		case FaultTimeout:
+			if fault.Latency <= 0 {
+				<-req.Context().Done()
+				return nil, req.Context().Err()
+			}
+			if err := wait(req.Context(), fault.Latency); err != nil {
+				return nil, err
+			}
+			return nil, &net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}
+		}
+	}
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	response, err := next.RoundTrip(req)
+	if err != nil || fault == nil || fault.Kind != FaultTruncate {
+		return response, err
+	}
+	response.Body = &truncatedBody{ReadCloser: response.Body, remain: fault.TruncateAfter}
+	response.ContentLength = -1
+	return response, nil
+}
+
+// Close body of request which is not sent
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+}
+
+// Select faults for request
+// Returns accumulated latency and first not latency fault
+func (t *FaultTransport) faults(req *http.Request) (latency time.Duration, fault *FaultRule) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.random == nil {
+		t.random = rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	}
+	if len(t.injections) != len(t.Rules) {
+		t.injections = make([]int, len(t.Rules))
+	}
+	if t.counters == nil {
+		t.counters = make(map[FaultKind]int)
+	}
+	for i := range t.Rules {
+		rule := &t.Rules[i]
+		if !rule.match(req) || (rule.Times > 0 && t.injections[i] >= rule.Times) {
+			continue
+		}
+		if rule.Kind != FaultLatency && fault != nil {
+			continue
+		}
+		if rule.Probability > 0 && t.random.Float64() >= rule.Probability {
+			continue
+		}
+		t.injections[i]++
+		t.counters[rule.Kind]++
+		if rule.Kind == FaultLatency {
+			latency += rule.Latency
+		} else {
+			fault = rule
+		}
+	}
+	return
+}
+
+// Check if request matches rule
+func (r *FaultRule) match(req *http.Request) bool {
+	if r.Method != "" && r.Method != req.Method {
+		return false
+	}
+	if r.Host != "" && r.Host != req.URL.Host {
+		return false
+	}
+	if r.Path != "" {
+		if ok, _ := path.Match(r.Path, req.URL.Path); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Body which fails after some bytes
+type truncatedBody struct {
+	io.ReadCloser
+	// Bytes left before error
+	remain int
+}
+
+// Read body until truncation
+func (b *truncatedBody) Read(p []byte) (int, error) {
+	if b.remain <= 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if len(p) > b.remain {
+		p = p[:b.remain]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remain -= n
+	return n, err
+}
+
+// Wait for delay or context done
+func wait(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
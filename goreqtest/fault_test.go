@@ -0,0 +1,120 @@
+package goreqtest
+
+import (
+	"github.com/dimonrus/goreq"
+	"github.com/dimonrus/porterr"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFaultTransportRetry(t *testing.T) {
+	mock := NewTransport()
+	mock.On(http.MethodGet, "/items").JSON([]int{1, 2})
+	fault := NewFaultTransport(1, mock,
+		FaultRule{Kind: FaultLatency, Latency: time.Millisecond * 20, Times: 1},
+		FaultRule{Kind: FaultStatus, Path: "/items", Times: 1},
+		FaultRule{Kind: FaultReset, Method: http.MethodGet, Times: 1},
+		FaultRule{Kind: FaultStatus, Host: "other.com"},
+	)
+	req := goreq.HttpRequest{Client: fault.Client(), Method: http.MethodGet, Host: "http://example.com", Url: "/items", RetryCount: 2}
+	start := time.Now()
+	response, body, err := goreq.Ensure(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != http.StatusOK || string(body) != "[1,2]" || time.Since(start) < time.Millisecond*20 {
+		t.Fatal("wrong response", response.StatusCode, string(body))
+	}
+	counters := fault.Counters()
+	if counters[FaultLatency] != 1 || counters[FaultStatus] != 1 || counters[FaultReset] != 1 || mock.Calls() != 1 {
+		t.Fatal("wrong counters", counters, mock.Calls())
+	}
+}
+
+func TestFaultTransportFailures(t *testing.T) {
+	mock := NewTransport()
+	mock.On(http.MethodGet, "/truncate").Body(strings.Repeat("x", 100))
+	fault := NewFaultTransport(1, mock,
+		FaultRule{Kind: FaultTruncate, Path: "/truncate", TruncateAfter: 10},
+		FaultRule{Kind: FaultTimeout, Path: "/timeout"},
+		FaultRule{Kind: FaultStatus, Path: "/limit", Status: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"1"}}},
+	)
+	req := goreq.HttpRequest{Client: fault.Client(), Method: http.MethodGet, Host: "http://example.com", Url: "/truncate"}
+	if _, _, err := goreq.Ensure(req); err == nil || !strings.Contains(err.Error(), "read error") {
+		t.Fatal("body must be truncated", err)
+	}
+	req.Url = "/timeout"
+	req.AttemptTimeout = time.Millisecond * 10
+	_, _, err := goreq.Ensure(req)
+	if e, ok := err.(porterr.IError); !ok || e.GetCode() != goreq.PortErrorTimeout {
+		t.Fatal("must be attempt timeout", err)
+	}
+	req.Url = "/limit"
+	response, _, _ := goreq.Ensure(req)
+	if response == nil || response.StatusCode != http.StatusTooManyRequests || response.Header.Get("Retry-After") != "1" {
+		t.Fatal("wrong status fault", response)
+	}
+	if fault.Injected(FaultTruncate) != 1 || fault.Injected(FaultTimeout) != 1 || fault.Injected(FaultStatus) != 1 {
+		t.Fatal("wrong counters", fault.Counters())
+	}
+}
+
+func TestFaultTransportSeed(t *testing.T) {
+	run := func(seed uint64) []int {
+		mock := NewTransport()
+		mock.On("", "")
+		fault := NewFaultTransport(seed, mock, FaultRule{Kind: FaultStatus, Probability: 0.5})
+		var statuses []int
+		for i := 0; i < 20; i++ {
+			req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+			response, err := fault.RoundTrip(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			statuses = append(statuses, response.StatusCode)
+		}
+		return statuses
+	}
+	first, second := run(42), run(42)
+	var injected int
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatal("runs with the same seed must be equal", first, second)
+		}
+		if first[i] == http.StatusServiceUnavailable {
+			injected++
+		}
+	}
+	if injected == 0 || injected == len(first) {
+		t.Fatal("faults must be injected by probability", first)
+	}
+}
+
+// Body with close tracking
+type trackedBody struct {
+	io.Reader
+	closed bool
+}
+
+func (b *trackedBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestFaultTransportCloseBody(t *testing.T) {
+	for _, kind := range []FaultKind{FaultStatus, FaultReset, FaultTimeout} {
+		fault := NewFaultTransport(1, NewTransport(), FaultRule{Kind: kind, Latency: time.Millisecond})
+		body := &trackedBody{Reader: strings.NewReader("{}")}
+		req, _ := http.NewRequest(http.MethodPost, "http://example.com/items", body)
+		response, _ := fault.RoundTrip(req)
+		if response != nil {
+			_ = response.Body.Close()
+		}
+		if !body.closed {
+			t.Fatal("request body must be closed", kind)
+		}
+	}
+}
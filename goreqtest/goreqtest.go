@@ -0,0 +1,322 @@
+// Package goreqtest Mock transport for testing code based on goreq
+package goreqtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// TestingT Part of testing.TB used by assertions
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// Transport Mock http.RoundTripper with expectations
+// Requests are matched with expectations in order of declaration
+type Transport struct {
+	mu sync.Mutex
+	// Declared expectations
+	expectations []*Expectation
+	// Requests without expectation
+	unexpected []string
+	// Count of all calls
+	calls int
+}
+
+// Expectation Expected request and canned responses
+type Expectation struct {
+	// Http method. Empty matches any method
+	method string
+	// Url path. Empty matches any path
+	path string
+	// Expected query parameters
+	query map[string][]string
+	// Expected headers
+	header http.Header
+	// Expected JSON body
+	jsonBody interface{}
+	// Has expected JSON body
+	hasJSONBody bool
+	// Custom matchers
+	matchers []func(req *http.Request) bool
+	// Canned responses. Last response repeats
+	responses []*response
+	// Expected count of calls. 0 - at least once
+	times int
+	// Count of calls
+	calls int
+	// Owner transport
+	transport *Transport
+}
+
+// Canned response
+type response struct {
+	// Status code
+	status int
+	// Headers
+	header http.Header
+	// Body
+	body []byte
+	// Delay before response
+	delay time.Duration
+	// Transport error
+	err error
+}
+
+// NewTransport Create mock transport
+func NewTransport() *Transport {
+	return &Transport{}
+}
+
+// Client Http client with mock transport
+func (t *Transport) Client() *http.Client {
+	return &http.Client{Transport: t}
+}
+
+// On Declare expectation of request with method and path
+func (t *Transport) On(method string, path string) *Expectation {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	e := &Expectation{method: method, path: path, header: make(http.Header), transport: t}
+	t.expectations = append(t.expectations, e)
+	return e
+}
+
+// Calls Count of all performed requests
+func (t *Transport) Calls() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.calls
+}
+
+// RoundTrip Serve request with canned response of matched expectation
+// Request without expectation returns an error
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+	}
+	t.mu.Lock()
+	t.calls++
+	var matched *Expectation
+	for _, e := range t.expectations {
+		if (e.times == 0 || e.calls < e.times) && e.match(req, body) {
+			matched = e
+			break
+		}
+	}
+	if matched == nil {
+		t.unexpected = append(t.unexpected, req.Method+" "+req.URL.String())
+		t.mu.Unlock()
+		return nil, fmt.Errorf("goreqtest: unexpected request %s %s", req.Method, req.URL)
+	}
+	r := &response{status: http.StatusOK}
+	if len(matched.responses) > 0 {
+		r = matched.responses[min(matched.calls, len(matched.responses)-1)]
+	}
+	matched.calls++
+	t.mu.Unlock()
+	if r.delay > 0 {
+		if err := wait(req.Context(), r.delay); err != nil {
+			return nil, err
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(r.status) + " " + http.StatusText(r.status),
+		StatusCode:    r.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(r.body)),
+		ContentLength: int64(len(r.body)),
+		Request:       req,
+	}, nil
+}
+
+// AssertExpectations Check that all expectations are met and there were no unexpected requests
+func (t *Transport) AssertExpectations(tt TestingT) bool {
+	tt.Helper()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ok := true
+	for _, e := range t.expectations {
+		if e.times > 0 && e.calls != e.times {
+			tt.Errorf("goreqtest: %s expected %d calls, got %d", e, e.times, e.calls)
+			ok = false
+		} else if e.times == 0 && e.calls == 0 {
+			tt.Errorf("goreqtest: %s was not called", e)
+			ok = false
+		}
+	}
+	for _, u := range t.unexpected {
+		tt.Errorf("goreqtest: unexpected request %s", u)
+		ok = false
+	}
+	return ok
+}
+
+// WithQuery Expect query parameter
+func (e *Expectation) WithQuery(name string, value string) *Expectation {
+	if e.query == nil {
+		e.query = make(map[string][]string)
+	}
+	e.query[name] = append(e.query[name], value)
+	return e
+}
+
+// WithHeader Expect header
+func (e *Expectation) WithHeader(name string, value string) *Expectation {
+	e.header.Add(name, value)
+	return e
+}
+
+// WithJSONBody Expect JSON body equal to value
+func (e *Expectation) WithJSONBody(value interface{}) *Expectation {
+	e.jsonBody = normalizeJSON(value)
+	e.hasJSONBody = true
+	return e
+}
+
+// WithMatcher Expect request matched with custom matcher
+// Matcher can read request body
+func (e *Expectation) WithMatcher(matcher func(req *http.Request) bool) *Expectation {
+	e.matchers = append(e.matchers, matcher)
+	return e
+}
+
+// Times Expect exact count of calls
+// Expectation is not matched after count is reached
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+// Once Expect exactly one call
+func (e *Expectation) Once() *Expectation {
+	return e.Times(1)
+}
+
+// Reply Add response with status code
+// Responses are returned in order of declaration, last response repeats
+func (e *Expectation) Reply(status int) *Expectation {
+	e.responses = append(e.responses, &response{status: status, header: make(http.Header)})
+	return e
+}
+
+// Header Set header of last response
+func (e *Expectation) Header(name string, value string) *Expectation {
+	e.last().header.Add(name, value)
+	return e
+}
+
+// Body Set body of last response
+func (e *Expectation) Body(body string) *Expectation {
+	e.last().body = []byte(body)
+	return e
+}
+
+// JSON Set JSON body and content type of last response
+func (e *Expectation) JSON(value interface{}) *Expectation {
+	r := e.last()
+	r.body, _ = json.Marshal(value)
+	r.header.Set("Content-Type", "application/json")
+	return e
+}
+
+// Delay Set delay of last response
+// Request context cancellation interrupts delay
+func (e *Expectation) Delay(d time.Duration) *Expectation {
+	e.last().delay = d
+	return e
+}
+
+// Error Add transport error instead of response
+func (e *Expectation) Error(err error) *Expectation {
+	e.responses = append(e.responses, &response{err: err, header: make(http.Header)})
+	return e
+}
+
+// Calls Count of matched requests
+func (e *Expectation) Calls() int {
+	e.transport.mu.Lock()
+	defer e.transport.mu.Unlock()
+	return e.calls
+}
+
+// String Description of expectation
+func (e *Expectation) String() string {
+	method, path := e.method, e.path
+	if method == "" {
+		method = "*"
+	}
+	if path == "" {
+		path = "*"
+	}
+	return method + " " + path
+}
+
+// Last response. Response 200 is added if there are no responses
+func (e *Expectation) last() *response {
+	if len(e.responses) == 0 {
+		e.Reply(http.StatusOK)
+	}
+	return e.responses[len(e.responses)-1]
+}
+
+// Check if request matches expectation
+func (e *Expectation) match(req *http.Request, body []byte) bool {
+	if e.method != "" && e.method != req.Method {
+		return false
+	}
+	if e.path != "" && e.path != req.URL.Path {
+		return false
+	}
+	query := req.URL.Query()
+	for name, values := range e.query {
+		if !reflect.DeepEqual(query[name], values) {
+			return false
+		}
+	}
+	for name := range e.header {
+		if !reflect.DeepEqual(req.Header.Values(name), e.header.Values(name)) {
+			return false
+		}
+	}
+	if e.hasJSONBody {
+		var actual interface{}
+		if json.Unmarshal(body, &actual) != nil || !reflect.DeepEqual(actual, e.jsonBody) {
+			return false
+		}
+	}
+	for _, matcher := range e.matchers {
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		if !matcher(req) {
+			return false
+		}
+	}
+	return true
+}
+
+// Convert value into generic JSON representation
+func normalizeJSON(value interface{}) interface{} {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var result interface{}
+	_ = json.Unmarshal(data, &result)
+	return result
+}
@@ -0,0 +1,107 @@
+package goreqtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dimonrus/goreq"
+	"github.com/dimonrus/porterr"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Recorder of assertion errors
+type recorderT struct {
+	errors []string
+}
+
+func (r *recorderT) Helper() {}
+
+func (r *recorderT) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestTransportRetry(t *testing.T) {
+	transport := NewTransport()
+	transport.On(http.MethodPost, "/items").
+		WithQuery("force", "true").
+		WithHeader("Content-Type", "application/json").
+		WithJSONBody(map[string]interface{}{"name": "item"}).
+		Error(errors.New("connection reset")).
+		Reply(http.StatusServiceUnavailable).
+		Reply(http.StatusCreated).JSON(map[string]int{"id": 1}).
+		Times(3)
+	req := goreq.HttpRequest{
+		Client:         transport.Client(),
+		Method:         http.MethodPost,
+		Host:           "http://example.com",
+		Url:            "/items?force=true",
+		Headers:        http.Header{"Content-Type": {"application/json"}},
+		Body:           []byte(`{ "name": "item" }`),
+		RetryCount:     2,
+		IdempotencyKey: true,
+	}
+	response, body, err := goreq.Ensure(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != http.StatusCreated || string(body) != `{"id":1}` || transport.Calls() != 3 {
+		t.Fatal("wrong response", response.StatusCode, string(body), transport.Calls())
+	}
+	transport.AssertExpectations(t)
+}
+
+func TestTransportAssertExpectations(t *testing.T) {
+	transport := NewTransport()
+	transport.On(http.MethodGet, "/items").Reply(http.StatusOK).Once()
+	transport.On(http.MethodGet, "/users")
+	req := goreq.HttpRequest{Client: transport.Client(), Method: http.MethodGet, Host: "http://example.com", Url: "/items"}
+	if _, _, err := goreq.Ensure(req); err != nil {
+		t.Fatal(err)
+	}
+	// Expectation is exhausted
+	if _, _, err := goreq.Ensure(req); err == nil || !strings.Contains(err.Error(), "unexpected request") {
+		t.Fatal("request must be unexpected", err)
+	}
+	r := &recorderT{}
+	if transport.AssertExpectations(r) || len(r.errors) != 2 {
+		t.Fatal("wrong assertion", r.errors)
+	}
+}
+
+func TestTransportDelay(t *testing.T) {
+	transport := NewTransport()
+	transport.On(http.MethodGet, "/slow").Delay(time.Second)
+	req := goreq.HttpRequest{Client: transport.Client(), Method: http.MethodGet, Host: "http://example.com", Url: "/slow", AttemptTimeout: time.Millisecond * 10}
+	_, _, err := goreq.EnsureContext(context.Background(), req)
+	if e, ok := err.(porterr.IError); !ok || e.GetCode() != goreq.PortErrorTimeout {
+		t.Fatal("must be attempt timeout", err)
+	}
+}
+
+func TestTransportPaginator(t *testing.T) {
+	type form struct {
+		goreq.Paginator
+	}
+	transport := NewTransport()
+	for page := 1; page <= 2; page++ {
+		transport.On(http.MethodGet, "/items").
+			WithJSONBody(map[string]interface{}{"page": page, "limit": 2, "parallelCount": 2}).
+			JSON(map[string]interface{}{
+				"data": []int{page*2 - 1, page * 2},
+				"meta": map[string]interface{}{"page": page, "limit": 2, "total": 4},
+			}).Once()
+	}
+	req := goreq.HttpRequest{Client: transport.Client(), Method: http.MethodGet, Host: "http://example.com", Url: "/items"}
+	f := form{Paginator: goreq.Paginator{Page: 1, Limit: 2, ParallelCount: 2}}
+	items, _, e := goreq.ParallelPaginatorJsonEnsure[form, int](f, req)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if len(items) != 4 {
+		t.Fatal("wrong items", items)
+	}
+	transport.AssertExpectations(t)
+}
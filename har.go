@@ -0,0 +1,419 @@
+package goreq
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// HARVersion Version of HTTP Archive format
+const HARVersion = "1.2"
+
+// HARRecorder Records requests and responses of each attempt in HTTP Archive format
+// Recorder can be shared between many requests
+type HARRecorder struct {
+	mu      sync.Mutex
+	entries []HAREntry
+}
+
+// HAR HTTP Archive
+type HAR struct {
+	// Archive log
+	Log HARLog `json:"log"`
+}
+
+// HARLog Log of HTTP Archive
+type HARLog struct {
+	// Format version
+	Version string `json:"version"`
+	// Log creator
+	Creator HARCreator `json:"creator"`
+	// Recorded entries
+	Entries []HAREntry `json:"entries"`
+}
+
+// HARCreator Application created the log
+type HARCreator struct {
+	// Application name
+	Name string `json:"name"`
+	// Application version. Empty for goreq which has no version constant
+	Version string `json:"version"`
+}
+
+// HAREntry Request and response of attempt
+type HAREntry struct {
+	// Start time of attempt
+	StartedDateTime time.Time `json:"startedDateTime"`
+	// Total time of attempt in milliseconds
+	Time float64 `json:"time"`
+	// Request
+	Request HARRequest `json:"request"`
+	// Response
+	Response HARResponse `json:"response"`
+	// Cache info. Always empty
+	Cache struct{} `json:"cache"`
+	// Attempt timings
+	Timings HARTimings `json:"timings"`
+	// Request label and attempt number
+	Comment string `json:"comment,omitempty"`
+	// Number of attempt starting from 0
+	Attempt uint `json:"_attempt"`
+	// Transport error
+	Error string `json:"_error,omitempty"`
+}
+
+// HARRequest Request of entry
+type HARRequest struct {
+	// Http method
+	Method string `json:"method"`
+	// Full url
+	URL string `json:"url"`
+	// Http version
+	HTTPVersion string `json:"httpVersion"`
+	// Cookies. Always empty, cookies are kept in headers
+	Cookies []HARNameValue `json:"cookies"`
+	// Headers
+	Headers []HARNameValue `json:"headers"`
+	// Query parameters
+	QueryString []HARNameValue `json:"queryString"`
+	// Request body
+	PostData *HARPostData `json:"postData,omitempty"`
+	// Size of headers. -1 - unknown
+	HeadersSize int `json:"headersSize"`
+	// Size of body
+	BodySize int `json:"bodySize"`
+}
+
+// HARResponse Response of entry
+type HARResponse struct {
+	// Status code. 0 when server does not respond
+	Status int `json:"status"`
+	// Status text
+	StatusText string `json:"statusText"`
+	// Http version
+	HTTPVersion string `json:"httpVersion"`
+	// Cookies. Always empty, cookies are kept in headers
+	Cookies []HARNameValue `json:"cookies"`
+	// Headers
+	Headers []HARNameValue `json:"headers"`
+	// Response body
+	Content HARContent `json:"content"`
+	// Redirect location
+	RedirectURL string `json:"redirectURL"`
+	// Size of headers. -1 - unknown
+	HeadersSize int `json:"headersSize"`
+	// Size of body
+	BodySize int `json:"bodySize"`
+}
+
+// HARNameValue Header, cookie or query parameter
+type HARNameValue struct {
+	// Name
+	Name string `json:"name"`
+	// Value
+	Value string `json:"value"`
+}
+
+// HARPostData Request body
+type HARPostData struct {
+	// Content type
+	MimeType string `json:"mimeType"`
+	// Body
+	Text string `json:"text"`
+	// Body truncated to LogBodySize or base64 encoded
+	Comment string `json:"comment,omitempty"`
+}
+
+// HARContent Response body
+type HARContent struct {
+	// Size of body
+	Size int `json:"size"`
+	// Content type
+	MimeType string `json:"mimeType"`
+	// Body
+	Text string `json:"text,omitempty"`
+	// Encoding of text. base64 for binary body
+	Encoding string `json:"encoding,omitempty"`
+	// Body truncated to LogBodySize
+	Comment string `json:"comment,omitempty"`
+}
+
+// HARTimings Timings of attempt in milliseconds
+// -1 when timing is not applicable
+type HARTimings struct {
+	// Waiting for connection
+	Blocked float64 `json:"blocked"`
+	// DNS resolution
+	DNS float64 `json:"dns"`
+	// Connection including TLS handshake
+	Connect float64 `json:"connect"`
+	// Sending request
+	Send float64 `json:"send"`
+	// Waiting for first byte of response
+	Wait float64 `json:"wait"`
+	// Receiving response
+	Receive float64 `json:"receive"`
+	// TLS handshake
+	SSL float64 `json:"ssl"`
+}
+
+// Entries Get recorded entries
+func (r *HARRecorder) Entries() []HAREntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entries := make([]HAREntry, len(r.entries))
+	copy(entries, r.entries)
+	return entries
+}
+
+// Reset Remove recorded entries
+func (r *HARRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = nil
+}
+
+// HAR Get HTTP Archive of recorded entries
+func (r *HARRecorder) HAR() HAR {
+	entries := r.Entries()
+	if entries == nil {
+		entries = []HAREntry{}
+	}
+	return HAR{Log: HARLog{Version: HARVersion, Creator: HARCreator{Name: "goreq"}, Entries: entries}}
+}
+
+// WriteTo Write HTTP Archive JSON into writer
+func (r *HARRecorder) WriteTo(w io.Writer) (int64, error) {
+	data, err := json.MarshalIndent(r.HAR(), "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// Add entry
+func (r *HARRecorder) add(entry HAREntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry)
+}
+
+// Points of attempt trace
+type harTrace struct {
+	mu           sync.Mutex
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	gotConn      time.Time
+	wrote        time.Time
+	firstByte    time.Time
+}
+
+// Client trace which sets trace points
+func (t *harTrace) clientTrace() *httptrace.ClientTrace {
+	set := func(point *time.Time) {
+		t.mu.Lock()
+		if point.IsZero() {
+			*point = time.Now()
+		}
+		t.mu.Unlock()
+	}
+	return &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { set(&t.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { set(&t.dnsDone) },
+		ConnectStart:         func(string, string) { set(&t.connectStart) },
+		ConnectDone:          func(string, string, error) { set(&t.connectDone) },
+		TLSHandshakeStart:    func() { set(&t.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { set(&t.tlsDone) },
+		GotConn:              func(httptrace.GotConnInfo) { set(&t.gotConn) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { set(&t.wrote) },
+		GotFirstResponseByte: func() { set(&t.firstByte) },
+	}
+}
+
+// Calculate timings of attempt
+func (t *harTrace) timings(start time.Time, end time.Time) HARTimings {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	timings := HARTimings{DNS: -1, Connect: -1, SSL: -1}
+	if !t.dnsStart.IsZero() && !t.dnsDone.IsZero() {
+		timings.DNS = harDuration(t.dnsStart, t.dnsDone)
+	}
+	if !t.connectStart.IsZero() && !t.connectDone.IsZero() {
+		connectDone := t.connectDone
+		if t.tlsDone.After(connectDone) {
+			connectDone = t.tlsDone
+		}
+		timings.Connect = harDuration(t.connectStart, connectDone)
+	}
+	if !t.tlsStart.IsZero() && !t.tlsDone.IsZero() {
+		timings.SSL = harDuration(t.tlsStart, t.tlsDone)
+	}
+	if t.gotConn.IsZero() || t.wrote.IsZero() || t.firstByte.IsZero() {
+		timings.Blocked = harDuration(start, end)
+		return timings
+	}
+	timings.Blocked = harDuration(start, t.gotConn) - max(timings.DNS, 0) - max(timings.Connect, 0)
+	if timings.Blocked < 0 {
+		timings.Blocked = 0
+	}
+	timings.Send = harDuration(t.gotConn, t.wrote)
+	timings.Wait = harDuration(t.wrote, t.firstByte)
+	timings.Receive = harDuration(t.firstByte, end)
+	return timings
+}
+
+// Duration between points in milliseconds
+func harDuration(from time.Time, to time.Time) float64 {
+	if to.Before(from) {
+		return 0
+	}
+	return float64(to.Sub(from).Microseconds()) / 1000
+}
+
+// HAR middleware
+// Records each attempt with timings into recorder
+func harMiddleware(request *HttpRequest) Middleware {
+	return func(next Handler) Handler {
+		if request.HAR == nil {
+			return next
+		}
+		return func(req *http.Request) (*http.Response, error) {
+			trace := &harTrace{}
+			req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace.clientTrace()))
+			start := time.Now()
+			response, err := next(req)
+			end := time.Now()
+			info, _ := AttemptFromContext(req.Context())
+			entry := HAREntry{
+				StartedDateTime: start,
+				Timings:         trace.timings(start, end),
+				Request:         harRequest(request, req),
+				Response:        HARResponse{Cookies: []HARNameValue{}, Headers: []HARNameValue{}, HeadersSize: -1, BodySize: -1},
+				Comment:         request.Label + " attempt #" + strconv.Itoa(int(info.Attempt)),
+				Attempt:         info.Attempt,
+			}
+			if info.Hedge > 0 {
+				entry.Comment += " hedge #" + strconv.Itoa(info.Hedge)
+			}
+			entry.Time = entry.Timings.Blocked + entry.Timings.Send + entry.Timings.Wait + entry.Timings.Receive +
+				max(entry.Timings.DNS, 0) + max(entry.Timings.Connect, 0)
+			if err != nil {
+				entry.Error = err.Error()
+				var readError *bodyReadError
+				if errors.As(err, &readError) {
+					entry.Response.Status = readError.status
+					entry.Response.StatusText = http.StatusText(readError.status)
+				}
+			} else {
+				entry.Response = harResponse(request, response)
+			}
+			request.HAR.add(entry)
+			return response, err
+		}
+	}
+}
+
+// Request of HAR entry
+func harRequest(request *HttpRequest, req *http.Request) HARRequest {
+	result := HARRequest{
+		Method:      req.Method,
+		URL:         request.redaction().URL(req.URL.String()),
+		HTTPVersion: req.Proto,
+		Cookies:     []HARNameValue{},
+		Headers:     harHeaders(request, req.Header),
+		QueryString: []HARNameValue{},
+		HeadersSize: -1,
+		BodySize:    len(request.Body),
+	}
+	if result.HTTPVersion == "" {
+		result.HTTPVersion = "HTTP/1.1"
+	}
+	var query url.Values
+	if u, err := url.Parse(result.URL); err == nil {
+		query = u.Query()
+	}
+	for _, name := range sortedKeys(query) {
+		for _, value := range query[name] {
+			result.QueryString = append(result.QueryString, HARNameValue{Name: name, Value: value})
+		}
+	}
+	if len(request.Body) > 0 {
+		text, encoding, comment := harBody(request, request.redaction().Body(request.Body, req.Header))
+		if encoding != "" && comment != "" {
+			comment = "base64 encoded, " + comment
+		} else if encoding != "" {
+			comment = "base64 encoded"
+		}
+		result.PostData = &HARPostData{MimeType: req.Header.Get("Content-Type"), Text: text, Comment: comment}
+	}
+	return result
+}
+
+// Response of HAR entry
+func harResponse(request *HttpRequest, response *http.Response) HARResponse {
+	body, _ := ResponseBody(response)
+	result := HARResponse{
+		Status:      response.StatusCode,
+		StatusText:  http.StatusText(response.StatusCode),
+		HTTPVersion: response.Proto,
+		Cookies:     []HARNameValue{},
+		Headers:     harHeaders(request, response.Header),
+		RedirectURL: response.Header.Get("Location"),
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+	result.Content.Size = len(body)
+	result.Content.MimeType = response.Header.Get("Content-Type")
+	result.Content.Text, result.Content.Encoding, result.Content.Comment = harBody(request, request.redaction().Body(body, response.Header))
+	return result
+}
+
+// Headers of HAR entry sorted by name
+func harHeaders(request *HttpRequest, header http.Header) []HARNameValue {
+	result := []HARNameValue{}
+	for _, name := range sortedKeys(header) {
+		for _, value := range header[name] {
+			result = append(result, HARNameValue{Name: name, Value: request.redaction().Header(name, value)})
+		}
+	}
+	return result
+}
+
+// Body of HAR entry truncated to LogBodySize
+// Binary body is base64 encoded
+func harBody(request *HttpRequest, body []byte) (text string, encoding string, comment string) {
+	binary := isBinary(body)
+	if request.LogBodySize > 0 && len(body) > request.LogBodySize {
+		size := len(body)
+		body = truncateBody(body, request.LogBodySize, binary)
+		comment = "body truncated to " + strconv.Itoa(len(body)) + " of " + strconv.Itoa(size) + " bytes"
+	}
+	if binary {
+		return base64.StdEncoding.EncodeToString(body), "base64", comment
+	}
+	return string(body), "", comment
+}
+
+// Sorted keys of map
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,109 @@
+package goreq
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestEnsureHAR(t *testing.T) {
+	var calls int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`{"message": "ok", "token": "secret"}`))
+	}))
+	defer s.Close()
+	recorder := &HARRecorder{}
+	req := HttpRequest{
+		Method:      http.MethodPost,
+		Host:        s.URL,
+		Url:         "/items?token=secret&page=1",
+		Label:       "har",
+		Headers:     http.Header{"Authorization": {"Bearer secret"}, "Content-Type": {"application/json"}},
+		Body:        []byte(`{"name":"item"}`),
+		RetryCount:  1,
+		LogBodySize: 8,
+		Redaction:   DefaultRedaction(),
+		HAR:         recorder,
+	}
+	if _, _, err := Ensure(req); err != nil {
+		t.Fatal(err)
+	}
+	entries := recorder.Entries()
+	if len(entries) != 2 {
+		t.Fatal("must be one entry per attempt", len(entries))
+	}
+	if entries[0].Response.Status != http.StatusBadGateway || entries[1].Response.Status != http.StatusOK || entries[1].Attempt != 1 {
+		t.Fatal("wrong entries", entries)
+	}
+	entry := entries[1]
+	if entry.Request.URL != s.URL+"/items?token=***&page=1" {
+		t.Fatal("url must be redacted", entry.Request.URL)
+	}
+	if len(entry.Request.QueryString) != 2 || entry.Request.QueryString[1] != (HARNameValue{Name: "token", Value: "***"}) {
+		t.Fatal("wrong query string", entry.Request.QueryString)
+	}
+	for _, h := range entry.Request.Headers {
+		if h.Name == "Authorization" && h.Value != "***" {
+			t.Fatal("header must be redacted", h)
+		}
+	}
+	if entry.Request.PostData == nil || entry.Request.PostData.Text != `{"name":` || entry.Request.PostData.Comment == "" {
+		t.Fatal("request body must be truncated", entry.Request.PostData)
+	}
+	if entry.Response.Content.Text != `{"messag` || entry.Response.Content.MimeType != "application/json" {
+		t.Fatal("wrong response content", entry.Response.Content)
+	}
+	if entry.Timings.Send < 0 || entry.Timings.Wait < 0 || entry.Timings.Receive < 0 || entry.Time <= 0 {
+		t.Fatal("wrong timings", entry.Timings, entry.Time)
+	}
+	buffer := &bytes.Buffer{}
+	if _, err := recorder.WriteTo(buffer); err != nil {
+		t.Fatal(err)
+	}
+	var har HAR
+	if err := json.Unmarshal(buffer.Bytes(), &har); err != nil {
+		t.Fatal(err)
+	}
+	if har.Log.Version != HARVersion || len(har.Log.Entries) != 2 {
+		t.Fatal("wrong archive", buffer.String())
+	}
+	recorder.Reset()
+	if len(recorder.Entries()) != 0 {
+		t.Fatal("entries must be removed")
+	}
+}
+
+func TestHARBody(t *testing.T) {
+	request := &HttpRequest{LogBodySize: 3}
+	text, encoding, comment := harBody(request, []byte("привет"))
+	if text != "п" || encoding != "" || comment != "body truncated to 2 of 12 bytes" {
+		t.Fatal("text must be truncated on character boundary", text, encoding, comment)
+	}
+	req := HttpRequest{Method: http.MethodPost, Url: "http://localhost/file", Body: []byte{0x00, 0xff, 0x10, 0x20}, LogBodySize: 3}
+	postData := harRequest(&req, httptest.NewRequest(http.MethodPost, req.Url, nil)).PostData
+	if postData == nil || postData.Comment != "base64 encoded, body truncated to 3 of 4 bytes" {
+		t.Fatal("truncation of binary body must be commented", postData)
+	}
+}
+
+func TestEnsureHARTransportError(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	s.Close()
+	recorder := &HARRecorder{}
+	req := HttpRequest{Method: http.MethodGet, Url: s.URL, RetryCount: 1, HAR: recorder}
+	if _, _, err := Ensure(req); err == nil {
+		t.Fatal("must be an error")
+	}
+	entries := recorder.Entries()
+	if len(entries) != 2 || entries[0].Error == "" || entries[0].Response.Status != 0 {
+		t.Fatal("failed attempts must be recorded", entries)
+	}
+}
@@ -0,0 +1,97 @@
+package goreq
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// HedgingPolicy Send additional identical attempts when response is late
+// First completed response wins, other attempts are canceled
+// Applied only for read-only methods: GET, HEAD, OPTIONS
+type HedgingPolicy struct {
+	// Delay before each additional attempt
+	Delay time.Duration
+	// Count of additional attempts. 1 if not defined
+	MaxHedges uint
+}
+
+// Count of additional attempts with default
+func (p *HedgingPolicy) hedges() int {
+	if p.MaxHedges == 0 {
+		return 1
+	}
+	return int(p.MaxHedges)
+}
+
+// Check if request with http method can be hedged
+func isHedgeable(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	default:
+		return false
+	}
+}
+
+// Result of hedged attempt
+type hedgeResult struct {
+	// Response of attempt
+	response *http.Response
+	// Attempt error
+	err error
+	// Index of attempt. 0 - original attempt
+	index int
+}
+
+// Hedging middleware
+// Additional attempt is sent after hedging delay if previous ones are not completed
+// First completed response wins, other attempts are canceled and awaited
+func hedgingMiddleware(r *HttpRequest) Middleware {
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			ctx, cancel := context.WithCancel(req.Context())
+			defer cancel()
+			info, _ := AttemptFromContext(ctx)
+			count := 1 + r.Hedging.hedges()
+			results := make(chan hedgeResult, count)
+			launch := func(index int) {
+				info.Hedge = index
+				hedgeRequest := cloneRequest(withAttempt(ctx, info), req)
+				go func() {
+					response, err := next(hedgeRequest)
+					results <- hedgeResult{response: response, err: err, index: index}
+				}()
+			}
+			launch(0)
+			launched, completed := 1, 0
+			timer := time.NewTimer(r.Hedging.Delay)
+			defer timer.Stop()
+			for {
+				select {
+				case <-timer.C:
+					if launched < count && ctx.Err() == nil {
+						launch(launched)
+						launched++
+						timer.Reset(r.Hedging.Delay)
+					}
+				case result := <-results:
+					completed++
+					if result.err == nil || completed == launched {
+						// canceled attempts must not use logger or recorder after return
+						cancel()
+						for ; completed < launched; completed++ {
+							if loser := <-results; loser.response != nil {
+								_ = loser.response.Body.Close()
+							}
+						}
+						if result.err == nil && launched > 1 && r.Logger != nil {
+							r.Logger.Printf("Hedged attempt #%v won. Service: %s", result.index, r.Label)
+						}
+						return result.response, result.err
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package goreq
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEnsureHedging(t *testing.T) {
+	var calls, canceled int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+				atomic.AddInt32(&canceled, 1)
+				return
+			case <-time.After(time.Second):
+			}
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer s.Close()
+	buffer := &bytes.Buffer{}
+	req := HttpRequest{
+		Method:  http.MethodGet,
+		Url:     s.URL,
+		Logger:  log.New(buffer, "", 0),
+		Hedging: &HedgingPolicy{Delay: time.Millisecond * 20},
+	}
+	start := time.Now()
+	response, body, err := Ensure(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != http.StatusOK || string(body) != "ok" {
+		t.Fatal("wrong response")
+	}
+	if time.Since(start) > time.Millisecond*500 {
+		t.Fatal("hedged attempt must win")
+	}
+	if !strings.Contains(buffer.String(), "Hedged attempt #1 won") {
+		t.Fatal("winner must be logged")
+	}
+	logs := buffer.Len()
+	time.Sleep(time.Millisecond * 50)
+	if atomic.LoadInt32(&canceled) != 1 {
+		t.Fatal("late attempt must be canceled")
+	}
+	if buffer.Len() != logs {
+		t.Fatal("canceled attempt must be completed before return")
+	}
+
+	for _, method := range []string{http.MethodPost, http.MethodPut} {
+		atomic.StoreInt32(&calls, 0)
+		req.Method = method
+		start = time.Now()
+		if _, _, err = Ensure(req); err != nil {
+			t.Fatal(err)
+		}
+		if time.Since(start) < time.Millisecond*500 || atomic.LoadInt32(&calls) != 1 {
+			t.Fatal("method must not be hedged", method)
+		}
+	}
+}
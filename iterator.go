@@ -0,0 +1,97 @@
+package goreq
+
+import (
+	"context"
+	"github.com/dimonrus/porterr"
+	"iter"
+	"sync"
+)
+
+// PaginatorPages Stream pages of paginator in order
+// Pages are prefetched in parallel with window of form parallel count, 0 - pages are requested one by one
+// Requests are stopped when consumer breaks the loop
+func PaginatorPages[F any, R any](ctx context.Context, form F, hr HttpRequest) iter.Seq2[[]R, error] {
+	return func(yield func([]R, error) bool) {
+		var _f interface{} = &form
+		var _form, ok = _f.(IPaginator)
+		if !ok {
+			yield(nil, porterr.New(porterr.PortErrorRequest, "form must implements IPaginator interface"))
+			return
+		}
+		items, meta, e := paginatorPage[F, R](ctx, hr, form)
+		if e != nil {
+			yield(nil, e)
+			return
+		}
+		if !yield(items, nil) {
+			return
+		}
+		if meta.Page == 0 {
+			meta.Page = 1
+		}
+		var pages []int
+		for page := meta.Page + 1; meta.Limit > 0 && (page-1)*meta.Limit < meta.Total; page++ {
+			pages = append(pages, page)
+		}
+		fetchPages[F, R](ctx, hr, form, pages, _form.GetParallelCount(), func(page int, items []R, e porterr.IError) bool {
+			return yield(items, e) && e == nil
+		})
+	}
+}
+
+// Request pages of paginator with bounded parallel window and yield them in order of pages
+// Outstanding requests are canceled when yield returns false
+func fetchPages[F any, R any](ctx context.Context, hr HttpRequest, form F, pages []int, window int, yield func(page int, items []R, e porterr.IError) bool) {
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+	window = max(window, 1)
+	results := make([]chan PaginatorResponse[R], len(pages))
+	fetch := func(i int) {
+		results[i] = make(chan PaginatorResponse[R], 1)
+		var p = form
+		var fp interface{} = &p
+		fp.(IPaginator).SetPage(pages[i])
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			items, meta, e := paginatorPage[F, R](ctx, hr, p)
+			results[i] <- PaginatorResponse[R]{Items: items, Meta: meta, Error: e}
+		}()
+	}
+	for i := 0; i < min(window, len(pages)); i++ {
+		fetch(i)
+	}
+	for i := range pages {
+		response := <-results[i]
+		results[i] = nil
+		if i+window < len(pages) {
+			fetch(i + window)
+		}
+		if !yield(pages[i], response.Items, response.Error) {
+			return
+		}
+	}
+}
+
+// PaginatorItems Stream items of paginator in order
+// Pages are requested as PaginatorPages does
+func PaginatorItems[F any, R any](ctx context.Context, form F, hr HttpRequest) iter.Seq2[R, error] {
+	return func(yield func(R, error) bool) {
+		for page, err := range PaginatorPages[F, R](ctx, form, hr) {
+			if err != nil {
+				var zero R
+				yield(zero, err)
+				return
+			}
+			for _, item := range page {
+				if !yield(item, nil) {
+					return
+				}
+			}
+		}
+	}
+}
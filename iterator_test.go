@@ -0,0 +1,65 @@
+package goreq
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPaginatorItems(t *testing.T) {
+	var calls int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		testPaginatorHandler(w, r)
+	}))
+	defer s.Close()
+	var total = 125
+	var limit = 13
+	hr := HttpRequest{Method: http.MethodPost, Url: s.URL + fmt.Sprintf("/?total=%v", total)}
+	for _, parallel := range []int{0, 3} {
+		atomic.StoreInt32(&calls, 0)
+		form := PaginatorRequestForm{Name: "item", Paginator: Paginator{Page: 2, Limit: limit, ParallelCount: parallel}}
+		var number = limit
+		for item, err := range PaginatorItems[PaginatorRequestForm, PaginatorTestItem](context.Background(), form, hr) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			if item.Number != number {
+				t.Fatal("wrong order", parallel, item.Number, number)
+			}
+			number++
+		}
+		if number != total || atomic.LoadInt32(&calls) != 9 {
+			t.Fatal("all items must be streamed", parallel, number, calls)
+		}
+	}
+	// Consumer breaks the loop
+	atomic.StoreInt32(&calls, 0)
+	form := PaginatorRequestForm{Paginator: Paginator{Page: 1, Limit: limit, ParallelCount: 2}}
+	var pages int
+	for page, err := range PaginatorPages[PaginatorRequestForm, PaginatorTestItem](context.Background(), form, hr) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) != limit {
+			t.Fatal("wrong page", page)
+		}
+		pages++
+		if pages == 2 {
+			break
+		}
+	}
+	// first page, second page and prefetch window after second page
+	if c := atomic.LoadInt32(&calls); c > 4 {
+		t.Fatal("requests must stop after break", c)
+	}
+	// Form without IPaginator
+	for _, err := range PaginatorItems[int, PaginatorTestItem](context.Background(), 1, hr) {
+		if err == nil {
+			t.Fatal("form must implement IPaginator")
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package goreq
+
+import (
+	"context"
+	"net/http"
+)
+
+// JSON Perform JSON request and decode response body into Resp
+// Body is marshaled and errors are the same as in EnsureJSON
+// Resp is zero value if response has no content
+func JSON[Resp any](ctx context.Context, r HttpRequest, method string, url string, body any) (Resp, *http.Response, error) {
+	var result Resp
+	req, err := r.jsonRequest(method, url, nil, body)
+	if err != nil {
+		return result, nil, err
+	}
+	response, data, err := EnsureContext(ctx, req)
+	if err != nil {
+		return result, response, err
+	}
+	if err = decodeJSON(req, response, data, &result); err != nil {
+		return result, nil, err
+	}
+	return result, response, nil
+}
+
+// JSONWithError Perform JSON request and decode response body into Resp
+// Body of non 2xx response is decoded into ErrBody, returned error is the error of ResponseErrorStrategy
+// Resp is zero value if response has no content
+func JSONWithError[Resp any, ErrBody any](ctx context.Context, r HttpRequest, method string, url string, body any) (Resp, ErrBody, *http.Response, error) {
+	var result Resp
+	var errBody ErrBody
+	req, err := r.jsonRequest(method, url, nil, body)
+	if err != nil {
+		return result, errBody, nil, err
+	}
+	response, data, err := EnsureContext(ctx, req)
+	if response == nil {
+		return result, errBody, nil, err
+	}
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		if len(data) > 0 {
+			if e := req.unmarshalJSON(data, &errBody); e != nil && err == nil {
+				err = e
+			}
+		}
+		return result, errBody, response, err
+	}
+	if err != nil {
+		return result, errBody, response, err
+	}
+	if err = decodeJSON(req, response, data, &result); err != nil {
+		return result, errBody, nil, err
+	}
+	return result, errBody, response, nil
+}
+
+// Decode response body into dto
+// Response without content is not decoded
+func decodeJSON(r HttpRequest, response *http.Response, data []byte, dto interface{}) error {
+	if response.StatusCode == http.StatusNoContent || len(data) == 0 {
+		return nil
+	}
+	return r.unmarshalJSON(data, dto)
+}
@@ -0,0 +1,73 @@
+package goreq
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/dimonrus/porterr"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	type item struct {
+		Id   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	type apiError struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/items":
+			var i item
+			_ = json.NewDecoder(r.Body).Decode(&i)
+			i.Id = 1
+			_ = json.NewEncoder(w).Encode(i)
+		case "/items/1":
+			w.WriteHeader(http.StatusNoContent)
+		case "/empty":
+		case "/broken":
+			_, _ = w.Write([]byte("not json"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"code":"NOT_FOUND","message":"item not found"}`))
+		}
+	}))
+	defer s.Close()
+	req := HttpRequest{Host: s.URL}
+	result, response, err := JSON[item](context.Background(), req, http.MethodPost, "/items", item{Name: "first"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != http.StatusOK || result != (item{Id: 1, Name: "first"}) {
+		t.Fatal("wrong result", result)
+	}
+	if _, _, err = JSON[item](context.Background(), req, http.MethodGet, "/broken", nil); err == nil || err.(porterr.IError).GetCode() != porterr.PortErrorBody {
+		t.Fatal("must be unmarshal error", err)
+	}
+	if _, _, err = JSON[item](context.Background(), req, http.MethodPost, "/items", func() {}); err == nil || err.(porterr.IError).GetCode() != porterr.PortErrorBody {
+		t.Fatal("must be marshal error", err)
+	}
+	result, errBody, response, err := JSONWithError[item, apiError](context.Background(), req, http.MethodGet, "/items/2", nil)
+	if err == nil || response.StatusCode != http.StatusNotFound || err.(porterr.IError).GetHTTP() != http.StatusNotFound {
+		t.Fatal("must be response error", err)
+	}
+	if errBody != (apiError{Code: "NOT_FOUND", Message: "item not found"}) || result != (item{}) {
+		t.Fatal("wrong error body", errBody)
+	}
+	result, errBody, _, err = JSONWithError[item, apiError](context.Background(), req, http.MethodPost, "/items", item{Name: "second"})
+	if err != nil || result.Name != "second" || errBody != (apiError{}) {
+		t.Fatal("wrong result", result, err)
+	}
+	// Response without content
+	for _, url := range []string{"/items/1", "/empty"} {
+		if _, response, err = JSON[struct{}](context.Background(), req, http.MethodDelete, url, nil); err != nil || response == nil {
+			t.Fatal("empty response must not be decoded", url, err)
+		}
+		if result, _, response, err = JSONWithError[item, apiError](context.Background(), req, http.MethodDelete, url, nil); err != nil || response == nil || result != (item{}) {
+			t.Fatal("empty response must not be decoded", url, err)
+		}
+	}
+}
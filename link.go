@@ -0,0 +1,206 @@
+package goreq
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/dimonrus/porterr"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// LinkPaginator Pagination by RFC 5988 Link header
+// Pages are followed by rel="next" links
+type LinkPaginator[R any] struct {
+	// Decode items of page. Body is decoded as JSON array if not defined
+	Decode func(response *http.Response, body []byte) ([]R, error)
+	// Query parameter of page number in links. page if not defined
+	PageParam string
+	// Count of parallel requests when rel="last" link has page number. 0 - pages are requested sequentially
+	ParallelCount int
+	// Max count of requested pages. 0 - unlimited
+	MaxPages int
+}
+
+// ParseLinkHeader Parse RFC 5988 Link header values into map of relation and url
+func ParseLinkHeader(values ...string) map[string]string {
+	links := make(map[string]string)
+	for _, value := range values {
+		for value != "" {
+			start := strings.IndexByte(value, '<')
+			end := strings.IndexByte(value, '>')
+			if start < 0 || end < start {
+				break
+			}
+			target := value[start+1 : end]
+			value = value[end+1:]
+			// parameters until next link
+			var params string
+			params, value = cutLinkParams(value)
+			for _, param := range strings.Split(params, ";") {
+				name, v, found := strings.Cut(strings.TrimSpace(param), "=")
+				if !found || !strings.EqualFold(strings.TrimSpace(name), "rel") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(strings.TrimSpace(v), `"`)) {
+					if _, ok := links[strings.ToLower(rel)]; !ok {
+						links[strings.ToLower(rel)] = target
+					}
+				}
+			}
+		}
+	}
+	return links
+}
+
+// Cut parameters of link until comma outside of quotes
+func cutLinkParams(value string) (params string, rest string) {
+	var quoted bool
+	for i, c := range value {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == ',' && !quoted:
+			return value[:i], value[i+1:]
+		}
+	}
+	return value, ""
+}
+
+// LinkPaginatorEnsure Request pages following rel="next" links of Link header
+// When ParallelCount is defined and rel="last" link has page number, remaining pages are requested in parallel
+// Links to other scheme or host are rejected
+func LinkPaginatorEnsure[R any](ctx context.Context, hr HttpRequest, paginator LinkPaginator[R]) (items []R, e porterr.IError) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	target := hr.Host + hr.Url
+	for page := 0; target != "" && (paginator.MaxPages == 0 || page < paginator.MaxPages); page++ {
+		var data []R
+		var links map[string]string
+		data, links, e = paginator.fetch(ctx, hr, target)
+		if e != nil {
+			return
+		}
+		items = append(items, data...)
+		target = links["next"]
+		if target == "" || paginator.ParallelCount == 0 {
+			continue
+		}
+		next, okNext := paginator.page(target)
+		last, okLast := paginator.page(links["last"])
+		if paginator.MaxPages > 0 {
+			last = min(last, next+paginator.MaxPages-page-2)
+		}
+		if !okNext || !okLast || last < next {
+			continue
+		}
+		var rest [][]R
+		rest, e = paginator.fetchParallel(ctx, cancel, hr, target, next, last)
+		for _, data = range rest {
+			items = append(items, data...)
+		}
+		return
+	}
+	return
+}
+
+// Fetch pages from first to last in parallel
+// First failed page cancels outstanding pages
+func (p LinkPaginator[R]) fetchParallel(ctx context.Context, cancel context.CancelFunc, hr HttpRequest, target string, first int, last int) (pages [][]R, e porterr.IError) {
+	pages = make([][]R, last-first+1)
+	var wg sync.WaitGroup
+	var once sync.Once
+	request := make(chan struct{}, p.ParallelCount)
+	for page := first; page <= last; page++ {
+		select {
+		case request <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(page int) {
+			defer wg.Done()
+			defer func() { <-request }()
+			data, _, err := p.fetch(ctx, hr, p.withPage(target, page))
+			if err != nil {
+				once.Do(func() {
+					e = err
+					cancel()
+				})
+				return
+			}
+			pages[page-first] = data
+		}(page)
+	}
+	wg.Wait()
+	if e == nil && ctx.Err() != nil {
+		e = canceledError(&hr, ctx.Err())
+	}
+	return
+}
+
+// Fetch page by url
+func (p LinkPaginator[R]) fetch(ctx context.Context, hr HttpRequest, target string) (items []R, links map[string]string, e porterr.IError) {
+	req := hr
+	req.Host, req.Url = "", target
+	base, err := url.Parse(hr.Host + hr.Url)
+	ref, refErr := url.Parse(target)
+	if err == nil && refErr == nil {
+		resolved := base.ResolveReference(ref)
+		// headers of request must not be sent to other host
+		if resolved.Scheme != base.Scheme || resolved.Host != base.Host {
+			e = porterr.NewF(porterr.PortErrorRequest, "Http Request (%s) link to other host is rejected: %s. Service: %s", hr.Url, hr.redaction().URL(resolved.String()), hr.Label)
+			return
+		}
+		req.Url = resolved.String()
+	}
+	response, body, err := EnsureContext(ctx, req)
+	if err != nil {
+		e = asPortError(err)
+		return
+	}
+	links = ParseLinkHeader(response.Header.Values("Link")...)
+	if p.Decode != nil {
+		items, err = p.Decode(response, body)
+	} else {
+		err = json.Unmarshal(body, &items)
+	}
+	if err != nil {
+		e = porterr.NewF(porterr.PortErrorBody, "Http Response (%s) marshal error: %s. Service: %s", req.Url, err, req.Label)
+	}
+	return
+}
+
+// Page number of link
+func (p LinkPaginator[R]) page(link string) (int, bool) {
+	u, err := url.Parse(link)
+	if link == "" || err != nil {
+		return 0, false
+	}
+	page, err := strconv.Atoi(u.Query().Get(p.pageParam()))
+	return page, err == nil
+}
+
+// Link with page number
+func (p LinkPaginator[R]) withPage(link string, page int) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	query := u.Query()
+	query.Set(p.pageParam(), strconv.Itoa(page))
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// Page parameter with default
+func (p LinkPaginator[R]) pageParam() string {
+	if p.PageParam == "" {
+		return "page"
+	}
+	return p.PageParam
+}
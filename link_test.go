@@ -0,0 +1,85 @@
+package goreq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParseLinkHeader(t *testing.T) {
+	links := ParseLinkHeader(`<https://api.example.com/items?page=2&a=1,2>; rel="next", <https://api.example.com/items?page=5>; rel="last"; title="a, b"`,
+		`<https://api.example.com/items?page=1>; REL="first prev"`)
+	if links["next"] != "https://api.example.com/items?page=2&a=1,2" || links["last"] != "https://api.example.com/items?page=5" {
+		t.Fatal("wrong links", links)
+	}
+	if links["first"] != "https://api.example.com/items?page=1" || links["prev"] != links["first"] || len(links) != 4 {
+		t.Fatal("wrong links", links)
+	}
+}
+
+func TestLinkPaginatorEnsure(t *testing.T) {
+	const pages = 5
+	var calls int32
+	var s *httptest.Server
+	s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+		if page < pages {
+			w.Header().Add("Link", fmt.Sprintf(`<%s/items?page=%d&fail=%s>; rel="next"`, s.URL, page+1, r.URL.Query().Get("fail")))
+			w.Header().Add("Link", fmt.Sprintf(`</items?page=%d>; rel="last"`, pages))
+		}
+		if r.URL.Query().Get("fail") == strconv.Itoa(page) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode([]int{page*2 - 1, page * 2})
+	}))
+	defer s.Close()
+	hr := HttpRequest{Method: http.MethodGet, Host: s.URL, Url: "/items"}
+	for _, parallel := range []int{0, 2} {
+		atomic.StoreInt32(&calls, 0)
+		items, e := LinkPaginatorEnsure(context.Background(), hr, LinkPaginator[int]{ParallelCount: parallel})
+		if e != nil {
+			t.Fatal(e)
+		}
+		if len(items) != pages*2 || atomic.LoadInt32(&calls) != pages {
+			t.Fatal("wrong items", parallel, items, calls)
+		}
+		for i, item := range items {
+			if item != i+1 {
+				t.Fatal("items must be ordered", parallel, items)
+			}
+		}
+		items, e = LinkPaginatorEnsure(context.Background(), hr, LinkPaginator[int]{ParallelCount: parallel, MaxPages: 3})
+		if e != nil || len(items) != 6 {
+			t.Fatal("max pages must stop pagination", parallel, items, e)
+		}
+	}
+	hr.Url = "/items?fail=3"
+	if _, e := LinkPaginatorEnsure(context.Background(), hr, LinkPaginator[int]{ParallelCount: 2}); e == nil {
+		t.Fatal("failed page must be an error")
+	}
+	// Link to other host
+	var foreign int32
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&foreign, 1)
+	}))
+	defer other.Close()
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Link", fmt.Sprintf(`<%s/items?page=2>; rel="next"`, other.URL))
+		_ = json.NewEncoder(w).Encode([]int{1})
+	}))
+	defer redirect.Close()
+	hr = HttpRequest{Method: http.MethodGet, Host: redirect.URL, Url: "/items", Headers: http.Header{"Authorization": {"secret"}}}
+	if _, e := LinkPaginatorEnsure(context.Background(), hr, LinkPaginator[int]{}); e == nil || atomic.LoadInt32(&foreign) != 0 {
+		t.Fatal("link to other host must be rejected", e)
+	}
+}
@@ -0,0 +1,174 @@
+package goreq
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ColorMode Colors of log output
+type ColorMode int
+
+const (
+	// ColorAuto Colors are enabled when stdout is a terminal
+	ColorAuto ColorMode = iota
+	// ColorAlways Colors are always enabled
+	ColorAlways
+	// ColorNever Colors are disabled
+	ColorNever
+)
+
+// ANSI colors
+const (
+	colorRed   = "\x1b[31;1m"
+	colorGreen = "\x1b[32;1m"
+	colorBlue  = "\x1b[34;1m"
+	colorReset = "\x1b[0m"
+)
+
+// Check if stdout is a terminal
+var isTerminal = sync.OnceValue(func() bool {
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+})
+
+// Enabled check if colors are enabled
+func (m ColorMode) Enabled() bool {
+	switch m {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	default:
+		return isTerminal()
+	}
+}
+
+// Paint text with color if colors are enabled
+func (m ColorMode) paint(color string, text string) string {
+	if !m.Enabled() {
+		return text
+	}
+	return color + text + colorReset
+}
+
+// Log middleware
+// Logs each attempt with request in CURL format, response status, time and body
+func logMiddleware(request *HttpRequest) Middleware {
+	return func(next Handler) Handler {
+		// Skip logging if not logger
+		if request.Logger == nil && request.StructuredLogger == nil {
+			return next
+		}
+		//Log request as CURL
+		var curl string
+		if request.Logger != nil {
+			curl = BuildCURL(*request)
+		}
+		return func(req *http.Request) (*http.Response, error) {
+			//Get start time
+			startTime := time.Now()
+			//Perform request
+			response, err := next(req)
+			//Calc delta
+			delta := time.Since(startTime).Milliseconds()
+			var status int
+			var body []byte
+			if err == nil {
+				status = response.StatusCode
+				body, _ = ResponseBody(response)
+				body = request.redaction().Body(body, response.Header)
+			} else {
+				var readError *bodyReadError
+				if errors.As(err, &readError) {
+					status = readError.status
+				}
+			}
+			logStructured(request, req, status, body, delta, err)
+			if request.Logger != nil {
+				if err != nil && status == 0 {
+					//if no response than log
+					request.Logger.Print(request.LogColor.paint(colorRed, curl+"\n "+err.Error()+" \n FAILED!!!"))
+				} else {
+					logRequest(request, status, &body, delta, curl)
+				}
+			}
+			return response, err
+		}
+	}
+}
+
+// Log request
+func logRequest(request *HttpRequest, responseStatus int, responseBody *[]byte, delta int64, curl string) {
+	// Skip logging if not logger
+	if request.Logger == nil {
+		return
+	}
+	//Log response status
+	logStatus := fmt.Sprintf("HTTP Status [%v] in: %v ms", responseStatus, delta)
+
+	//Log response body
+	logBody := "Body: " + bodyExcerpt(*responseBody, request.LogBodySize)
+
+	//If response status code more than 300 shows in red
+	color := colorGreen
+	if responseStatus >= 300 {
+		color = colorRed
+	}
+	logStatus = request.LogColor.paint(color, logStatus)
+	logBody = request.LogColor.paint(color, logBody)
+	request.Logger.Print("\n    ", request.LogColor.paint(colorBlue, curl), "\n    ", logStatus, "\n    ", logBody)
+}
+
+// Log attempt as structured record
+func logStructured(request *HttpRequest, req *http.Request, status int, body []byte, delta int64, err error) {
+	if request.StructuredLogger == nil {
+		return
+	}
+	level := slog.LevelInfo
+	if err != nil || status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	} else if status >= http.StatusBadRequest {
+		level = slog.LevelWarn
+	}
+	ctx := req.Context()
+	if !request.StructuredLogger.Enabled(ctx, level) {
+		return
+	}
+	info, _ := AttemptFromContext(ctx)
+	attrs := []slog.Attr{
+		slog.String("label", request.Label),
+		slog.String("method", req.Method),
+		slog.String("url", request.redaction().URL(req.URL.String())),
+		slog.Int("status", status),
+		slog.Int64("duration_ms", delta),
+		slog.Uint64("attempt", uint64(info.Attempt)),
+	}
+	if info.Hedge > 0 {
+		attrs = append(attrs, slog.Int("hedge", info.Hedge))
+	}
+	if len(request.Body) > 0 {
+		attrs = append(attrs, slog.String("request_body", bodyExcerpt(request.redaction().Body(request.Body, request.Headers), request.LogBodySize)))
+	}
+	if len(body) > 0 {
+		attrs = append(attrs, slog.String("response_body", bodyExcerpt(body, request.LogBodySize)))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	request.StructuredLogger.LogAttrs(ctx, level, "http request", attrs...)
+}
+
+// Body prepared for log
+// Whitespaces are collapsed, body is truncated to size
+func bodyExcerpt(body []byte, size int) string {
+	if size == 0 || len(body) < size {
+		return strings.Join(strings.Fields(string(body)), " ")
+	}
+	return strings.Join(strings.Fields(string(body[:size-1])), " ") + "..."
+}
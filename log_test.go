@@ -0,0 +1,90 @@
+package goreq
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestEnsureStructuredLogger(t *testing.T) {
+	var calls int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"message": "ok"}`))
+	}))
+	defer s.Close()
+	buffer := &bytes.Buffer{}
+	req := HttpRequest{
+		Method:           http.MethodPost,
+		Url:              s.URL,
+		Label:            "structured",
+		Body:             []byte(`{"name":"item"}`),
+		RetryCount:       1,
+		StructuredLogger: slog.New(slog.NewJSONHandler(buffer, nil)),
+	}
+	if _, _, err := Ensure(req); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatal("must be one record per attempt", lines)
+	}
+	var record struct {
+		Level        string `json:"level"`
+		Label        string `json:"label"`
+		Method       string `json:"method"`
+		Status       int    `json:"status"`
+		Attempt      int    `json:"attempt"`
+		RequestBody  string `json:"request_body"`
+		ResponseBody string `json:"response_body"`
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.Level != "INFO" || record.Label != "structured" || record.Method != http.MethodPost || record.Status != http.StatusOK || record.Attempt != 1 {
+		t.Fatal("wrong record", lines[1])
+	}
+	if record.RequestBody != `{"name":"item"}` || record.ResponseBody != `{"message": "ok"}` {
+		t.Fatal("wrong record body", lines[1])
+	}
+	if !strings.Contains(lines[0], `"level":"ERROR"`) {
+		t.Fatal("failed attempt must be logged as error", lines[0])
+	}
+}
+
+func TestLogColor(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer s.Close()
+	buffer := &bytes.Buffer{}
+	req := HttpRequest{
+		Method:   http.MethodGet,
+		Url:      s.URL,
+		Logger:   log.New(buffer, "", 0),
+		LogColor: ColorNever,
+	}
+	if _, _, err := Ensure(req); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buffer.String(), "\x1b[") || !strings.Contains(buffer.String(), "HTTP Status [200]") {
+		t.Fatal("colors must be disabled", buffer.String())
+	}
+	buffer.Reset()
+	req.LogColor = ColorAlways
+	if _, _, err := Ensure(req); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buffer.String(), colorGreen) {
+		t.Fatal("colors must be enabled", buffer.String())
+	}
+}
@@ -0,0 +1,110 @@
+package goreq
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+)
+
+// Handler Perform http request
+// Body of returned response is buffered and can be received with ResponseBody
+type Handler func(req *http.Request) (*http.Response, error)
+
+// Middleware Wrap handler with additional behavior
+// Middleware can modify request before next handler and response or error after
+type Middleware func(next Handler) Handler
+
+// AttemptInfo Information about current attempt
+type AttemptInfo struct {
+	// Number of attempt starting from 0
+	Attempt uint
+	// Index of hedged attempt. 0 - original attempt
+	Hedge int
+}
+
+// Context key of attempt info
+type attemptKey struct{}
+
+// AttemptFromContext Get attempt info from request context
+// Available in per attempt interceptors
+func AttemptFromContext(ctx context.Context) (AttemptInfo, bool) {
+	info, ok := ctx.Value(attemptKey{}).(AttemptInfo)
+	return info, ok
+}
+
+// Set attempt info into context
+func withAttempt(ctx context.Context, info AttemptInfo) context.Context {
+	return context.WithValue(ctx, attemptKey{}, info)
+}
+
+// Buffered response body
+type bufferedBody struct {
+	*bytes.Reader
+	// Body data
+	data []byte
+}
+
+// Close body
+func (b *bufferedBody) Close() error {
+	return nil
+}
+
+// ResponseBody Get body of response without consuming it
+// Not buffered body is read and replaced by buffered one
+func ResponseBody(response *http.Response) ([]byte, error) {
+	if response == nil || response.Body == nil {
+		return nil, nil
+	}
+	if body, ok := response.Body.(*bufferedBody); ok {
+		return body.data, nil
+	}
+	data, err := io.ReadAll(response.Body)
+	_ = response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	response.Body = newBufferedBody(data)
+	return data, nil
+}
+
+// Create buffered body
+func newBufferedBody(data []byte) *bufferedBody {
+	return &bufferedBody{Reader: bytes.NewReader(data), data: data}
+}
+
+// Error when response body can not be read
+type bodyReadError struct {
+	// Response status code
+	status int
+	// Read error
+	err error
+}
+
+// Error message
+func (e *bodyReadError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap read error
+func (e *bodyReadError) Unwrap() error {
+	return e.err
+}
+
+// Clone request for new attempt with fresh body
+func cloneRequest(ctx context.Context, req *http.Request) *http.Request {
+	clone := req.Clone(ctx)
+	if req.GetBody != nil {
+		clone.Body, _ = req.GetBody()
+	}
+	return clone
+}
+
+// Wrap handler with middlewares
+// First middleware is the outermost
+func chain(handler Handler, middlewares ...Middleware) Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
@@ -0,0 +1,69 @@
+package goreq
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestEnsureInterceptors(t *testing.T) {
+	var calls int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer s.Close()
+	var attempts []uint
+	var callCount int
+	var body string
+	req := HttpRequest{
+		Method:     http.MethodGet,
+		Url:        s.URL,
+		RetryCount: 2,
+		Interceptors: []Middleware{
+			func(next Handler) Handler {
+				return func(req *http.Request) (*http.Response, error) {
+					info, _ := AttemptFromContext(req.Context())
+					attempts = append(attempts, info.Attempt)
+					return next(req)
+				}
+			},
+		},
+		CallInterceptors: []Middleware{
+			func(next Handler) Handler {
+				return func(req *http.Request) (*http.Response, error) {
+					callCount++
+					req.Header = req.Header.Clone()
+					if req.Header == nil {
+						req.Header = make(http.Header)
+					}
+					req.Header.Set("Authorization", "Bearer token")
+					response, err := next(req)
+					if err == nil {
+						data, _ := ResponseBody(response)
+						body = string(data)
+					}
+					return response, err
+				}
+			},
+		},
+	}
+	response, data, err := Ensure(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != http.StatusOK || string(data) != "ok" || body != "ok" {
+		t.Fatal("wrong response")
+	}
+	if callCount != 1 || len(attempts) != 2 || attempts[0] != 0 || attempts[1] != 1 {
+		t.Fatal("wrong interceptors calls", callCount, attempts)
+	}
+}
@@ -1,8 +1,11 @@
 package goreq
 
 import (
+	"context"
 	"github.com/dimonrus/gorest"
 	"github.com/dimonrus/porterr"
+	"sort"
+	"strconv"
 )
 
 // IPaginator interface
@@ -75,3 +78,95 @@ type PaginatorResponse[T any] struct {
 	// Error
 	Error porterr.IError
 }
+
+// PaginatorFailureMode Handling of failed pages
+type PaginatorFailureMode int
+
+const (
+	// PaginatorFailFast First failed page cancels outstanding pages and returns error
+	PaginatorFailFast PaginatorFailureMode = iota
+	// PaginatorContinue Failed pages are skipped and reported, other pages are collected
+	PaginatorContinue
+)
+
+// PaginatorOptions Options of parallel paginator
+type PaginatorOptions struct {
+	// Handling of failed pages
+	FailureMode PaginatorFailureMode
+	// Count of failed page retries. Page retries are independent of request retries
+	PageRetryCount uint
+	// Delay between page retries. ConstantBackoff with RetryTimeout of request if not defined
+	PageBackoff Backoff
+	// Check if failed page can be retried. All errors are retried if not defined
+	PageRetryPolicy func(page int, e porterr.IError) bool
+}
+
+// PageError Error of failed page
+type PageError struct {
+	// Page number
+	Page int
+	// Error of the last page attempt
+	Error porterr.IError
+}
+
+// PaginatorReport Report of paginator
+type PaginatorReport struct {
+	// Failed pages missing in items ordered by page
+	Failed []PageError
+}
+
+// Err Error with failed pages as details. nil if all pages are fetched
+func (r PaginatorReport) Err() porterr.IError {
+	if len(r.Failed) == 0 {
+		return nil
+	}
+	e := porterr.NewF(porterr.PortErrorResponse, "Paginator has %d failed pages", len(r.Failed))
+	for _, failed := range r.Failed {
+		e = e.PushDetail(failed.Error.GetCode(), strconv.Itoa(failed.Page), failed.Error.Error())
+	}
+	return e
+}
+
+// Request page with retries of failed page
+func paginatorPageRetry[F any, R any](ctx context.Context, hr HttpRequest, form F, options PaginatorOptions) (data []R, meta gorest.Meta, e porterr.IError) {
+	var page int
+	var fp interface{} = &form
+	if p, ok := fp.(IPaginator); ok {
+		page = p.GetPage()
+	}
+	backoff := options.PageBackoff
+	if backoff == nil {
+		backoff = ConstantBackoff{Interval: hr.RetryTimeout}
+	}
+	for attempt := uint(0); ; attempt++ {
+		data, meta, e = paginatorPage[F, R](ctx, hr, form)
+		if e == nil || attempt >= options.PageRetryCount || ctx.Err() != nil {
+			return
+		}
+		if options.PageRetryPolicy != nil && !options.PageRetryPolicy(page, e) {
+			return
+		}
+		if sleep(ctx, backoff.Delay(attempt, nil, e)) != nil {
+			return
+		}
+	}
+}
+
+// Remove items of failed pages from result
+// Failed pages are sorted by page
+func compactPages[R any](result []R, report PaginatorReport, first int, limit int) []R {
+	if len(report.Failed) == 0 {
+		return result
+	}
+	sort.Slice(report.Failed, func(i, j int) bool {
+		return report.Failed[i].Page < report.Failed[j].Page
+	})
+	items := make([]R, 0, len(result)-len(report.Failed)*limit)
+	var start int
+	for _, failed := range report.Failed {
+		end := (failed.Page - first) * limit
+		items = append(items, result[start:end]...)
+		start = min(end+limit, len(result))
+	}
+	return append(items, result[start:]...)
+}
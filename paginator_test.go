@@ -0,0 +1,134 @@
+package goreq
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/dimonrus/porterr"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParallelPaginatorJsonEnsureOptions(t *testing.T) {
+	var mu sync.Mutex
+	// count of failures per page
+	var failures map[int]int
+	setFailures := func(f map[int]int) {
+		mu.Lock()
+		failures = f
+		mu.Unlock()
+	}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var p Paginator
+		_ = json.Unmarshal(data, &p)
+		mu.Lock()
+		fail := failures[p.Page] > 0
+		failures[p.Page]--
+		mu.Unlock()
+		if fail {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(data))
+		testPaginatorHandler(w, r)
+	}))
+	defer s.Close()
+	var total = 100
+	var limit = 10
+	hr := HttpRequest{Method: http.MethodPost, Url: s.URL + fmt.Sprintf("/?total=%v", total)}
+	form := PaginatorRequestForm{Paginator: Paginator{Page: 1, Limit: limit, ParallelCount: 3}}
+	numbers := func(items []PaginatorTestItem) (result []int) {
+		for _, item := range items {
+			result = append(result, item.Number/limit)
+		}
+		return
+	}
+
+	// Fail fast
+	setFailures(map[int]int{4: 1})
+	_, _, _, e := ParallelPaginatorJsonEnsureOptions[PaginatorRequestForm, PaginatorTestItem](context.Background(), form, hr, PaginatorOptions{})
+	if e == nil {
+		t.Fatal("failed page must be an error")
+	}
+
+	// Retry failed pages
+	setFailures(map[int]int{4: 2, 7: 1})
+	items, _, report, e := ParallelPaginatorJsonEnsureOptions[PaginatorRequestForm, PaginatorTestItem](context.Background(), form, hr, PaginatorOptions{PageRetryCount: 2})
+	if e != nil || len(items) != total || len(report.Failed) != 0 || report.Err() != nil {
+		t.Fatal("failed pages must be retried", e, len(items), report)
+	}
+
+	// Continue with report
+	setFailures(map[int]int{4: 5, 10: 5})
+	var retried []int
+	options := PaginatorOptions{
+		FailureMode:    PaginatorContinue,
+		PageRetryCount: 1,
+		PageRetryPolicy: func(page int, e porterr.IError) bool {
+			mu.Lock()
+			retried = append(retried, page)
+			mu.Unlock()
+			return page == 4
+		},
+	}
+	items, _, report, e = ParallelPaginatorJsonEnsureOptions[PaginatorRequestForm, PaginatorTestItem](context.Background(), form, hr, options)
+	if e != nil {
+		t.Fatal(e)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(report.Failed) != 2 || report.Failed[0].Page != 4 || report.Failed[1].Page != 10 || failures[4] != 3 || failures[10] != 4 {
+		t.Fatal("wrong report", report, failures)
+	}
+	if len(report.Err().GetDetails()) != 2 || len(retried) != 2 {
+		t.Fatal("wrong report error", report.Err(), retried)
+	}
+	if len(items) != total-2*limit {
+		t.Fatal("items of failed pages must be skipped", numbers(items))
+	}
+	for i, item := range items {
+		page := item.Number/limit + 1
+		if page == 4 || page == 10 || (i > 0 && item.Number <= items[i-1].Number) {
+			t.Fatal("wrong items", numbers(items))
+		}
+	}
+}
+
+func TestParallelPaginatorJsonEnsureOptionsCancel(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Millisecond * 10)
+		testPaginatorHandler(w, r)
+	}))
+	defer s.Close()
+	var total = 1000
+	var limit = 10
+	hr := HttpRequest{Method: http.MethodPost, Url: s.URL + fmt.Sprintf("/?total=%v", total)}
+	form := PaginatorRequestForm{Paginator: Paginator{Page: 1, Limit: limit, ParallelCount: 2}}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*60)
+	defer cancel()
+	done := make(chan struct{})
+	var items []PaginatorTestItem
+	var report PaginatorReport
+	var e porterr.IError
+	go func() {
+		defer close(done)
+		items, _, report, e = ParallelPaginatorJsonEnsureOptions[PaginatorRequestForm, PaginatorTestItem](ctx, form, hr, PaginatorOptions{FailureMode: PaginatorContinue})
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second * 5):
+		t.Fatal("canceled paginator must return")
+	}
+	if e == nil || e.GetCode() != PortErrorCanceled {
+		t.Fatal("cancel must be an error", e)
+	}
+	if len(report.Failed) == 0 || len(items)+len(report.Failed)*limit != total {
+		t.Fatal("not completed pages must be reported", len(items), len(report.Failed))
+	}
+}
@@ -0,0 +1,157 @@
+package goreq
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// DefaultRedactMask Value that replaces sensitive data
+const DefaultRedactMask = "***"
+
+// Redaction Policy of sensitive data masking in logs and CURL
+// Empty policy does not mask anything
+type Redaction struct {
+	// Header names to mask. Case insensitive
+	Headers []string
+	// Query parameters to mask
+	QueryParams []string
+	// JSON field paths to mask. Segments are separated by dot
+	// * - matches any field, ** - matches any count of nested fields, arrays are skipped
+	// e.g. user.password, items.*.token, **.secret
+	JSONFields []string
+	// Replacement of sensitive data. DefaultRedactMask if not defined
+	Mask string
+	// Custom body redactor. Called after JSON fields masking
+	Redactor func(body []byte, header http.Header) []byte
+}
+
+// DefaultRedaction Redaction policy with common sensitive headers, query parameters and JSON fields
+func DefaultRedaction() *Redaction {
+	return &Redaction{
+		Headers:     []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "X-Auth-Token"},
+		QueryParams: []string{"api_key", "apikey", "access_token", "token", "password", "secret"},
+		JSONFields:  []string{"**.password", "**.access_token", "**.refresh_token", "**.token", "**.secret", "**.api_key"},
+	}
+}
+
+// Default policy of requests. Never modified
+var defaultRedaction = DefaultRedaction()
+
+// Redaction policy of request with default
+func (r HttpRequest) redaction() *Redaction {
+	if r.Redaction == nil {
+		return defaultRedaction
+	}
+	return r.Redaction
+}
+
+// Header Mask header value if header is sensitive
+func (p *Redaction) Header(name string, value string) string {
+	if p == nil {
+		return value
+	}
+	for _, h := range p.Headers {
+		if strings.EqualFold(h, name) {
+			return p.mask()
+		}
+	}
+	return value
+}
+
+// URL Mask sensitive query parameters
+// Order of parameters is kept
+func (p *Redaction) URL(rawURL string) string {
+	if p == nil || len(p.QueryParams) == 0 {
+		return rawURL
+	}
+	base, query, found := strings.Cut(rawURL, "?")
+	if !found {
+		return rawURL
+	}
+	query, fragment, hasFragment := strings.Cut(query, "#")
+	params := strings.Split(query, "&")
+	for i, param := range params {
+		key, _, _ := strings.Cut(param, "=")
+		for _, q := range p.QueryParams {
+			if key == q {
+				params[i] = key + "=" + p.mask()
+				break
+			}
+		}
+	}
+	rawURL = base + "?" + strings.Join(params, "&")
+	if hasFragment {
+		rawURL += "#" + fragment
+	}
+	return rawURL
+}
+
+// Body Mask sensitive JSON fields and apply custom redactor
+// Body which is not JSON is passed to custom redactor only
+func (p *Redaction) Body(body []byte, header http.Header) []byte {
+	if p == nil || len(body) == 0 {
+		return body
+	}
+	if len(p.JSONFields) > 0 {
+		var data interface{}
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		if decoder.Decode(&data) == nil {
+			var masked bool
+			for _, field := range p.JSONFields {
+				data = p.maskJSON(data, strings.Split(field, "."), &masked)
+			}
+			if masked {
+				if result, err := json.Marshal(data); err == nil {
+					body = result
+				}
+			}
+		}
+	}
+	if p.Redactor != nil {
+		body = p.Redactor(bytes.Clone(body), header)
+	}
+	return body
+}
+
+// Mask JSON value matched by path
+func (p *Redaction) maskJSON(data interface{}, path []string, masked *bool) interface{} {
+	if len(path) == 0 {
+		*masked = true
+		return p.mask()
+	}
+	switch value := data.(type) {
+	case []interface{}:
+		for i := range value {
+			value[i] = p.maskJSON(value[i], path, masked)
+		}
+	case map[string]interface{}:
+		segment := path[0]
+		if segment == "**" {
+			// path continues on the same level or deeper
+			data = p.maskJSON(value, path[1:], masked)
+			if object, ok := data.(map[string]interface{}); ok {
+				for k := range object {
+					object[k] = p.maskJSON(object[k], path, masked)
+				}
+			}
+			return data
+		}
+		for k := range value {
+			if segment == "*" || segment == k {
+				value[k] = p.maskJSON(value[k], path[1:], masked)
+			}
+		}
+	}
+	return data
+}
+
+// Mask with default
+func (p *Redaction) mask() string {
+	if p.Mask == "" {
+		return DefaultRedactMask
+	}
+	return p.Mask
+}
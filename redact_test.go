@@ -0,0 +1,68 @@
+package goreq
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRedaction(t *testing.T) {
+	p := DefaultRedaction()
+	if p.Header("authorization", "Bearer secret") != DefaultRedactMask || p.Header("Accept", "*/*") != "*/*" {
+		t.Fatal("wrong header redaction")
+	}
+	if u := p.URL("http://host/path?page=1&token=abc&x=2#top"); u != "http://host/path?page=1&token=***&x=2#top" {
+		t.Fatal("wrong url redaction", u)
+	}
+	body := p.Body([]byte(`{"user":{"name":"john","password":"123"},"items":[{"token":"t1"},{"token":"t2"}]}`), nil)
+	if string(body) != `{"items":[{"token":"***"},{"token":"***"}],"user":{"name":"john","password":"***"}}` {
+		t.Fatal("wrong body redaction", string(body))
+	}
+	p = &Redaction{JSONFields: []string{"data.*.secret"}, Mask: "[hidden]"}
+	body = p.Body([]byte(`{"data":{"a":{"secret":1},"b":{"secret":2,"public":3}},"secret":4}`), nil)
+	if string(body) != `{"data":{"a":{"secret":"[hidden]"},"b":{"public":3,"secret":"[hidden]"}},"secret":4}` {
+		t.Fatal("wrong path redaction", string(body))
+	}
+	p.Redactor = func(body []byte, header http.Header) []byte {
+		return bytes.ReplaceAll(body, []byte("pin=1234"), []byte("pin=****"))
+	}
+	if body = p.Body([]byte("user=john&pin=1234"), nil); string(body) != "user=john&pin=****" {
+		t.Fatal("wrong custom redaction", string(body))
+	}
+	if (HttpRequest{}).redaction().Header("Authorization", "secret") != DefaultRedactMask {
+		t.Fatal("default policy must mask")
+	}
+	if (HttpRequest{Redaction: &Redaction{}}).redaction().Header("Authorization", "secret") != "secret" {
+		t.Fatal("empty policy must not mask")
+	}
+}
+
+func TestEnsureRedaction(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"access_token":"server-token"}`))
+	}))
+	defer s.Close()
+	buffer := &bytes.Buffer{}
+	req := HttpRequest{
+		Method:  http.MethodPost,
+		Url:     s.URL + "?api_key=key",
+		Headers: http.Header{"Authorization": {"Bearer client-token"}},
+		Body:    []byte(`{"password":"pass"}`),
+		Logger:  log.New(buffer, "", 0),
+	}
+	_, data, err := Ensure(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"access_token":"server-token"}` {
+		t.Fatal("response must not be modified")
+	}
+	for _, secret := range []string{"client-token", "server-token", "pass\"", "=key"} {
+		if strings.Contains(buffer.String(), secret) {
+			t.Fatal("secret is logged", secret, buffer.String())
+		}
+	}
+}
@@ -2,12 +2,13 @@ package goreq
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"fmt"
 	"github.com/dimonrus/gorest"
 	"github.com/dimonrus/porterr"
 	"io"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"strings"
@@ -17,6 +18,17 @@ import (
 // DefaultTimeout Default request timeout
 const DefaultTimeout = 30
 
+// Error codes
+const (
+	// PortErrorCanceled Request context was canceled or deadline exceeded
+	PortErrorCanceled = "PORTABLE_ERROR_CANCELED"
+	// PortErrorTimeout Request attempt or total timeout exceeded
+	// Error name contains exhausted budget: attempt or total
+	PortErrorTimeout = "PORTABLE_ERROR_TIMEOUT"
+	// PortErrorCircuitOpen Request rejected by circuit breaker
+	PortErrorCircuitOpen = "PORTABLE_ERROR_CIRCUIT_OPEN"
+)
+
 // Logger Request logger interface
 // Implement default logger methods
 type Logger interface {
@@ -45,8 +57,36 @@ type HttpRequest struct {
 	RetryCount uint
 	//Retry timeout. Default 30s
 	RetryTimeout time.Duration
+	//Backoff strategy for delay between attempts
+	//Constant RetryTimeout if not defined
+	Backoff Backoff
+	//Wait for delay requested by server in Retry-After or X-RateLimit-Reset headers
+	//429 Too Many Requests becomes retryable
+	RespectRetryAfter bool
+	//Max delay requested by server. 0 - without limit
+	MaxRetryAfter time.Duration
+	//Timeout of each attempt including response body read. 0 - only client timeout
+	AttemptTimeout time.Duration
+	//Timeout of all attempts and delays between them. 0 - without limit
+	TotalTimeout time.Duration
+	//Circuit breaker. Circuit is defined by Label or Host
+	CircuitBreaker *CircuitBreaker
+	//Hedging policy. Sends additional attempts for GET, HEAD and OPTIONS methods when response is late
+	Hedging *HedgingPolicy
+	//Interceptors executed for each attempt
+	Interceptors []Middleware
+	//Interceptors executed once for request around all attempts
+	CallInterceptors []Middleware
+	//Retry budget. Limits retries of requests with the same Label or Host
+	RetryBudget *RetryBudget
 	//Retry strategy callback
 	RetryStrategy func(response *http.Response) bool
+	//Retry policy callback. Receives performed request with response or transport error
+	//Default policy checks responses with RetryStrategy and transport errors with IdempotentRetry
+	RetryPolicy func(attempt RetryAttempt) bool
+	//Generate Idempotency-Key header for non-idempotent methods
+	//Key is the same for all attempts of the request
+	IdempotencyKey bool
 	//Response error
 	ResponseErrorStrategy func(response *http.Response) error
 	//Logger. Implements RequestLogger
@@ -54,6 +94,21 @@ type HttpRequest struct {
 	//How many body bytes must be logged
 	//0 - all body will be logged
 	LogBodySize int
+	//Redaction policy of sensitive data in logs and CURL. DefaultRedaction if not defined, empty policy to disable
+	Redaction *Redaction
+	//Structured logger. Logs one record per attempt
+	StructuredLogger *slog.Logger
+	//Colors of Logger output. Enabled for terminal by default
+	LogColor ColorMode
+	//HAR recorder. Records each attempt in HTTP Archive format
+	HAR *HARRecorder
+	//Cassette. Records responses or replays them instead of performing requests
+	Cassette *Cassette
+
+	//Performed attempts of the call
+	attempts []AttemptResult
+	//Retries of the call were exhausted
+	exhausted bool
 }
 
 // Validate request
@@ -103,25 +158,6 @@ func responseError(response *http.Response) error {
 	return e
 }
 
-// BuildCURL Build curl for logging
-func BuildCURL(request HttpRequest) string {
-	b := strings.Builder{}
-	b.WriteString("curl -X " + request.Method + " '" + request.Host + request.Url + "'")
-	//Collect headers
-	for k, v := range request.Headers {
-		b.WriteString(" -H '" + k + ": " + strings.Join(v, ",") + "' ")
-	}
-	// log body
-	if request.Body != nil {
-		if request.LogBodySize == 0 || len(request.Body) < request.LogBodySize {
-			b.WriteString("-d '" + string(request.Body) + "'")
-		} else {
-			b.WriteString("-d '" + string(request.Body[:request.LogBodySize-1]) + "...'")
-		}
-	}
-	return b.String()
-}
-
 func initDefault(request *HttpRequest) {
 	//Check http client
 	if request.Client == nil {
@@ -131,6 +167,14 @@ func initDefault(request *HttpRequest) {
 	if request.RetryStrategy == nil {
 		request.RetryStrategy = canContinueRetry
 	}
+	//Check retry policy
+	if request.RetryPolicy == nil {
+		request.RetryPolicy = defaultRetryPolicy(request.RetryStrategy)
+	}
+	//Check backoff strategy
+	if request.Backoff == nil {
+		request.Backoff = ConstantBackoff{Interval: request.RetryTimeout}
+	}
 	//Check error response strategy
 	if request.ResponseErrorStrategy == nil {
 		request.ResponseErrorStrategy = responseError
@@ -144,6 +188,12 @@ func (r *HttpRequest) InitDefaultLogger() {
 
 // Ensure request
 func Ensure(request HttpRequest) (*http.Response, []byte, error) {
+	return EnsureContext(context.Background(), request)
+}
+
+// EnsureContext ensure request with context
+// Context is attached to every attempt and interrupts sleep between retries
+func EnsureContext(ctx context.Context, request HttpRequest) (*http.Response, []byte, error) {
 	//Validate request
 	if err := request.validate(); err != nil {
 		return nil, nil, err
@@ -151,122 +201,145 @@ func Ensure(request HttpRequest) (*http.Response, []byte, error) {
 	//Set default options
 	initDefault(&request)
 
+	//Set idempotency key
+	if request.IdempotencyKey && !IsIdempotent(request.Method) && request.Headers.Get(IdempotencyKeyHeader) == "" {
+		request.Headers = request.Headers.Clone()
+		if request.Headers == nil {
+			request.Headers = make(http.Header)
+		}
+		request.Headers.Set(IdempotencyKeyHeader, newIdempotencyKey())
+	}
+
+	//Limit total time of all attempts
+	parent := ctx
+	if request.TotalTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, request.TotalTimeout)
+		defer cancel()
+	}
+
 	//Make new request
-	req, err := http.NewRequest(request.Method, request.Host+request.Url, nil)
+	req, err := http.NewRequestWithContext(ctx, request.Method, request.Host+request.Url, bytes.NewReader(request.Body))
 	if err != nil {
 		return nil, nil, porterr.NewF(porterr.PortErrorRequest, "Http Request build error: %s. Service: %s", err, request.Label)
 	}
 
 	req.Header = request.Headers
 
-	//Log request as CURL
-	var logCurl string
-	if request.Logger != nil {
-		logCurl = BuildCURL(request)
+	//Attempt handler
+	attempt := chain(request.transport, logMiddleware(&request), harMiddleware(&request), cassetteMiddleware(&request))
+	attempt = chain(attempt, request.Interceptors...)
+	if request.Hedging != nil && isHedgeable(request.Method) {
+		attempt = hedgingMiddleware(&request)(attempt)
 	}
 
-	// Response
-	var response *http.Response
+	//Logical call handler
+	call := chain(retryMiddleware(&request, parent)(attempt), request.CallInterceptors...)
 
-	// Response body
-	var bodyBytes []byte
-
-	// Body buffer
-	var buffer *bytes.Buffer
-
-	//Calculate request time
-	var startTime, endTime, delta int64
-
-	//Loop for retry count
-	for i := uint(0); i <= request.RetryCount; i++ {
-		//Set body
-		buffer = bytes.NewBuffer(request.Body)
-		req.Body = io.NopCloser(buffer)
-		//Get start time
-		startTime = time.Now().UnixNano()
-		//Perform request
-		response, err = request.Client.Do(req)
-		//Get end time
-		endTime = time.Now().UnixNano()
-		//Calc delta
-		delta = (endTime - startTime) / int64(time.Millisecond)
-		//If server does not respond
-		if err != nil {
-			//if no response than log
-			if request.Logger != nil {
-				request.Logger.Printf("\x1b[31;1m"+logCurl+"\n %s \n FAILED!!!\x1b[0m", err)
-			}
-			if i >= request.RetryCount {
-				return nil, nil, porterr.NewF(porterr.PortErrorSystem, "Http Request (%s) failed. Service: %s, Error: %s", request.Url, request.Label, err)
-			}
-			if request.RetryTimeout.Nanoseconds() > 0 {
-				time.Sleep(request.RetryTimeout)
-			}
-		} else {
-			// Read response
-			bodyBytes, err = io.ReadAll(response.Body)
-			_ = response.Body.Close()
-			if err != nil {
-				bodyBytes = []byte{}
-				logRequest(&request, response.StatusCode, &bodyBytes, delta, logCurl)
-				return nil, nil, porterr.NewF(porterr.PortErrorResponse, "Http Response (%s) read error: %s. Service: %s", request.Host+request.Url, err, request.Label)
-			}
-			//Log request
-			logRequest(&request, response.StatusCode, &bodyBytes, delta, logCurl)
-
-			//Check if you can retry the response
-			if request.RetryStrategy(response) {
-				//Sleep before next round
-				if request.RetryTimeout.Nanoseconds() > 0 {
-					time.Sleep(request.RetryTimeout)
-				}
-				continue
-			} else {
-				break
-			}
-		}
+	//Perform request
+	response, err := call(req)
+	if err != nil {
+		return nil, nil, newRequestError(&request, err)
+	}
+	if response == nil {
+		return nil, nil, porterr.NewF(porterr.PortErrorResponse, "Http Request (%s) has no response. Service: %s", request.Url, request.Label)
 	}
 
-	if response != nil {
-		response.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+	// Response body
+	bodyBytes, err := ResponseBody(response)
+	if err != nil {
+		return nil, nil, porterr.NewF(porterr.PortErrorResponse, "Http Response (%s) read error: %s. Service: %s", request.Host+request.Url, err, request.Label)
 	}
+	response.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+
 	if request.ResponseErrorStrategy != nil {
-		err = request.ResponseErrorStrategy(response)
+		if err = request.ResponseErrorStrategy(response); err != nil {
+			err = newRequestError(&request, err)
+		}
 	}
 
 	return response, bodyBytes, err
 }
 
-// Log request
-func logRequest(request *HttpRequest, responseStatus int, responseBody *[]byte, delta int64, curl string) {
-	// Skip logging if not logger
-	if request.Logger == nil {
-		return
+// Perform single attempt limited by attempt timeout
+// Response body is read, closed and buffered
+func (r *HttpRequest) transport(req *http.Request) (*http.Response, error) {
+	if r.AttemptTimeout > 0 {
+		ctx, cancel := context.WithTimeout(req.Context(), r.AttemptTimeout)
+		defer cancel()
+		req = req.WithContext(ctx)
 	}
-	//Log response status
-	logStatus := fmt.Sprintf("HTTP Status [%v] in: %v ms", responseStatus, delta)
-
-	//Log response body
-	var logBody string
-	if request.LogBodySize == 0 || len(*responseBody) < request.LogBodySize {
-		logBody = fmt.Sprintf("Body: %s", strings.Join(strings.Fields(string(*responseBody)), " "))
-	} else {
-		logBody = fmt.Sprintf("Body: %s...", strings.Join(strings.Fields(string(*responseBody)[:request.LogBodySize-1]), " "))
+	response, err := r.Client.Do(req)
+	if err != nil {
+		return nil, err
 	}
+	body, err := io.ReadAll(response.Body)
+	_ = response.Body.Close()
+	if err != nil {
+		return nil, &bodyReadError{status: response.StatusCode, err: err}
+	}
+	response.Body = newBufferedBody(body)
+	return response, nil
+}
 
-	//If response status code more than 300 shows in red
-	if responseStatus >= 300 {
-		logStatus = "\x1b[31;1m" + logStatus + "\x1b[0m"
-		logBody = "\x1b[31;1m" + logBody + "\x1b[0m"
-	} else {
-		logStatus = "\x1b[32;1m" + logStatus + "\x1b[0m"
-		logBody = "\x1b[32;1m" + logBody + "\x1b[0m"
+// Sleep for duration or until context is done
+func sleep(ctx context.Context, duration time.Duration) error {
+	if duration <= 0 {
+		return ctx.Err()
 	}
-	request.Logger.Print("\n    ", "\x1b[34;1m"+curl+"\x1b[0m", "\n    ", logStatus, "\n    ", logBody)
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Error when request timeout exceeded
+// budget is the name of exhausted timeout: attempt or total
+func timeoutError(request *HttpRequest, budget string, timeout time.Duration) porterr.IError {
+	return porterr.NewFWithName(PortErrorTimeout, budget, "Http Request (%s) %s timeout %s exceeded. Service: %s", request.Url, budget, timeout, request.Label).HTTP(http.StatusGatewayTimeout)
+}
+
+// Error when request context is canceled
+func canceledError(request *HttpRequest, err error) porterr.IError {
+	return porterr.NewF(PortErrorCanceled, "Http Request (%s) canceled: %s. Service: %s", request.Url, err, request.Label)
 }
 
 // EnsureJSON ensure JSON request
 func (r HttpRequest) EnsureJSON(method string, url string, header http.Header, body interface{}, dto interface{}) (*http.Response, error) {
+	return r.EnsureJSONContext(context.Background(), method, url, header, body, dto)
+}
+
+// EnsureJSONContext ensure JSON request with context
+func (r HttpRequest) EnsureJSONContext(ctx context.Context, method string, url string, header http.Header, body interface{}, dto interface{}) (*http.Response, error) {
+	// Prepare request
+	req, err := r.jsonRequest(method, url, header, body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ensure
+	response, data, err := EnsureContext(ctx, req)
+	if err != nil {
+		return response, err
+	}
+
+	// Unmarshal response
+	if dto != nil {
+		err = req.unmarshalJSON(data, dto)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return response, nil
+}
+
+// Copy request with method, url, additional headers and marshaled body
+func (r HttpRequest) jsonRequest(method string, url string, header http.Header, body interface{}) (HttpRequest, error) {
 	// Error interface
 	var err error
 
@@ -282,6 +355,9 @@ func (r HttpRequest) EnsureJSON(method string, url string, header http.Header, b
 	//Copy headers
 	req.Headers = r.Headers.Clone()
 	if header != nil {
+		if req.Headers == nil {
+			req.Headers = make(http.Header)
+		}
 		for key, value := range header {
 			req.Headers.Add(key, strings.Join(value, ","))
 		}
@@ -295,42 +371,48 @@ func (r HttpRequest) EnsureJSON(method string, url string, header http.Header, b
 		//Marshal body
 		req.Body, err = json.Marshal(body)
 		if err != nil {
-			return nil, porterr.NewF(porterr.PortErrorBody, "Http Request (%s) marshal error: %s. Service: %s", req.Host+req.Url, err.Error(), req.Label)
+			return req, porterr.NewF(porterr.PortErrorBody, "Http Request (%s) marshal error: %s. Service: %s", req.Host+req.Url, err.Error(), req.Label)
 		}
 	}
+	return req, nil
+}
 
-	// Ensure
-	response, data, err := Ensure(req)
+// Unmarshal response body into dto
+func (r HttpRequest) unmarshalJSON(data []byte, dto interface{}) error {
+	err := json.Unmarshal(data, dto)
 	if err != nil {
-		return response, err
+		return porterr.NewF(porterr.PortErrorBody, "Http Response (%s) marshal error: %s. Service: %s", r.Host+r.Url, err.Error(), r.Label)
 	}
-
-	// Unmarshal response
-	if dto != nil {
-		err = json.Unmarshal(data, dto)
-		if err != nil {
-			return nil, porterr.NewF(porterr.PortErrorBody, "Http Response (%s) marshal error: %s. Service: %s", req.Host+req.Url, err.Error(), req.Label)
-		}
-	}
-
-	return response, nil
+	return nil
 }
 
 // ParallelPaginatorJsonEnsure Execute api call that can have async count of parallel request
 func ParallelPaginatorJsonEnsure[F any, R any](form F, hr HttpRequest) (items []R, meta gorest.Meta, e porterr.IError) {
+	return ParallelPaginatorJsonEnsureContext[F, R](context.Background(), form, hr)
+}
+
+// ParallelPaginatorJsonEnsureContext Execute api call that can have async count of parallel request
+// First failed page cancels all outstanding page requests
+func ParallelPaginatorJsonEnsureContext[F any, R any](ctx context.Context, form F, hr HttpRequest) (items []R, meta gorest.Meta, e porterr.IError) {
+	items, meta, _, e = ParallelPaginatorJsonEnsureOptions[F, R](ctx, form, hr, PaginatorOptions{})
+	return
+}
+
+// ParallelPaginatorJsonEnsureOptions Execute api call that can have async count of parallel request
+// Failed pages are retried and handled according to options
+// Report contains pages which are missing in items
+// Canceled context is an error, items and report contain pages completed before cancel
+func ParallelPaginatorJsonEnsureOptions[F any, R any](ctx context.Context, form F, hr HttpRequest, options PaginatorOptions) (items []R, meta gorest.Meta, report PaginatorReport, e porterr.IError) {
 	var _f interface{} = &form
 	var _form, ok = _f.(IPaginator)
 	if !ok {
 		e = porterr.New(porterr.PortErrorRequest, "form must implements IPaginator interface")
 		return
 	}
-	call := func(requestForm F) (data []R, meta gorest.Meta, e porterr.IError) {
-		response := gorest.JsonResponse{Data: &data, Meta: &meta}
-		_, err := hr.EnsureJSON(hr.Method, hr.Url, nil, requestForm, &response)
-		if err != nil {
-			e = err.(*porterr.PortError)
-		}
-		return
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	call := func(requestForm F) ([]R, gorest.Meta, porterr.IError) {
+		return paginatorPageRetry[F, R](ctx, hr, requestForm, options)
 	}
 	items, meta, e = call(form)
 	// return on condition
@@ -367,16 +449,27 @@ func ParallelPaginatorJsonEnsure[F any, R any](form F, hr HttpRequest) (items []
 			var p = form
 			var fp interface{} = &p
 			fp.(IPaginator).SetPage(iterator + meta.Page)
-			request <- struct{}{}
-			go func(f chan PaginatorResponse[R], p F) {
+			select {
+			case request <- struct{}{}:
+			case <-ctx.Done():
+				// pages which were not requested are canceled
+				for page := iterator + meta.Page; page <= respLen+meta.Page; page++ {
+					fetch <- PaginatorResponse[R]{Meta: gorest.Meta{Page: page}, Error: canceledError(&hr, ctx.Err())}
+				}
+				return
+			}
+			go func(f chan PaginatorResponse[R], p F, page int) {
 				items, meta, e := call(p)
+				if e != nil {
+					meta.Page = page
+				}
 				f <- PaginatorResponse[R]{
 					Items: items,
 					Meta:  meta,
 					Error: e,
 				}
 				<-request
-			}(fetch, p)
+			}(fetch, p, iterator+meta.Page)
 		}
 	}()
 	// save data according to order
@@ -385,16 +478,33 @@ func ParallelPaginatorJsonEnsure[F any, R any](form F, hr HttpRequest) (items []
 	var processed int
 	for response := range fetch {
 		if response.Error != nil {
-			e = response.Error
-			return
+			if options.FailureMode == PaginatorFailFast {
+				e = response.Error
+				return
+			}
+			report.Failed = append(report.Failed, PageError{Page: response.Meta.Page, Error: response.Error})
+		} else {
+			copy(result[(response.Meta.Page-meta.Page)*meta.Limit:(response.Meta.Page-meta.Page)*meta.Limit+len(response.Items)], response.Items)
 		}
-		copy(result[(response.Meta.Page-meta.Page)*meta.Limit:(response.Meta.Page-meta.Page)*meta.Limit+len(response.Items)], response.Items)
 		processed++
 		if processed == respLen {
 			close(fetch)
 			break
 		}
 	}
-	items = result
+	items = compactPages(result, report, meta.Page, meta.Limit)
+	if len(report.Failed) > 0 && ctx.Err() != nil {
+		e = canceledError(&hr, ctx.Err())
+	}
+	return
+}
+
+// Request page of paginator
+func paginatorPage[F any, R any](ctx context.Context, hr HttpRequest, form F) (data []R, meta gorest.Meta, e porterr.IError) {
+	response := gorest.JsonResponse{Data: &data, Meta: &meta}
+	_, err := hr.EnsureJSONContext(ctx, hr.Method, hr.Url, nil, form, &response)
+	if err != nil {
+		e = asPortError(err)
+	}
 	return
 }
@@ -1,6 +1,7 @@
 package goreq
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/dimonrus/gorest"
@@ -12,6 +13,7 @@ import (
 	"os"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -294,7 +296,7 @@ func TestGoreqError(t *testing.T) {
 	if err == nil {
 		t.Fatal("error await")
 	}
-	e := err.(*porterr.PortError)
+	e := err.(porterr.IError)
 	fmt.Println(e.GetDetails())
 	fmt.Println(len(e.GetDetails()))
 }
@@ -477,3 +479,124 @@ func TestBuildCURL(t *testing.T) {
 	curl := BuildCURL(localholder)
 	t.Log(curl)
 }
+
+func TestEnsureContextCanceled(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer s.Close()
+	req := HttpRequest{
+		Method:       http.MethodGet,
+		Host:         s.URL,
+		Url:          "/",
+		Label:        "canceled",
+		RetryCount:   10,
+		RetryTimeout: time.Second,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	start := time.Now()
+	_, _, err := EnsureContext(ctx, req)
+	if err == nil {
+		t.Fatal("error await")
+	}
+	if err.(porterr.IError).GetCode() != PortErrorCanceled {
+		t.Fatal("wrong error code", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatal("retry sleep must be interrupted")
+	}
+}
+
+func TestParallelPaginatorJsonEnsureContextFailed(t *testing.T) {
+	var calls int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p Paginator
+		_ = json.NewDecoder(r.Body).Decode(&p)
+		atomic.AddInt32(&calls, 1)
+		if p.Page == 3 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if p.Page > 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}
+		ok := gorest.NewOkJsonResponse("paginator", []PaginatorTestItem{{Number: p.Page}}, gorest.Meta{Page: p.Page, Limit: 1, Total: 100})
+		data, _ := json.Marshal(ok)
+		_, _ = w.Write(data)
+	}))
+	defer s.Close()
+	hr := HttpRequest{Method: http.MethodPost, Url: s.URL, Label: "paginator"}
+	form := PaginatorRequestForm{Paginator: Paginator{Page: 1, Limit: 1, ParallelCount: 2}}
+	start := time.Now()
+	_, _, e := ParallelPaginatorJsonEnsureContext[PaginatorRequestForm, PaginatorTestItem](context.Background(), form, hr)
+	if e == nil {
+		t.Fatal("error await")
+	}
+	if time.Since(start) > time.Millisecond*900 {
+		t.Fatal("outstanding pages must be canceled")
+	}
+	if atomic.LoadInt32(&calls) > 10 {
+		t.Fatal("pages must not be requested after failure")
+	}
+}
+
+func TestEnsureTimeouts(t *testing.T) {
+	var calls int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/unavailable" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Path == "/slow" || atomic.AddInt32(&calls, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+	req := HttpRequest{
+		Method:         http.MethodGet,
+		Host:           s.URL,
+		Url:            "/",
+		RetryCount:     1,
+		AttemptTimeout: time.Millisecond * 50,
+	}
+	response, _, err := Ensure(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != http.StatusOK || calls != 2 {
+		t.Fatal("slow attempt must be retried")
+	}
+
+	req.Url = "/slow"
+	_, _, err = Ensure(req)
+	if err == nil {
+		t.Fatal("error await")
+	}
+	if e := err.(porterr.IError); e.GetCode() != PortErrorTimeout || e.Origin().Name != "attempt" {
+		t.Fatal("wrong attempt timeout error", err)
+	}
+
+	req.Url = "/unavailable"
+	req.RetryCount = 10
+	req.RetryTimeout = time.Millisecond * 100
+	req.TotalTimeout = time.Millisecond * 250
+	start := time.Now()
+	_, _, err = Ensure(req)
+	if err == nil {
+		t.Fatal("error await")
+	}
+	if e := err.(porterr.IError); e.GetCode() != PortErrorTimeout || e.Origin().Name != "total" {
+		t.Fatal("wrong total timeout error", err)
+	}
+	if time.Since(start) > time.Millisecond*250 {
+		t.Fatal("total timeout exceeded")
+	}
+}
@@ -0,0 +1,255 @@
+package goreq
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"github.com/dimonrus/porterr"
+	"log/slog"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// IdempotencyKeyHeader Header with key of logical request
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// RetryAttempt Result of performed attempt
+type RetryAttempt struct {
+	// Number of attempt starting from 0
+	Attempt uint
+	// Performed request
+	Request *http.Request
+	// Response of attempt. nil if transport error
+	Response *http.Response
+	// Transport error
+	Error error
+}
+
+// IdempotentRetry Retry transport errors only when it is safe
+// Idempotent methods and requests with Idempotency-Key header are always retried
+// Other requests are retried only if the error proves that request was not sent
+func IdempotentRetry(attempt RetryAttempt) bool {
+	if attempt.Error == nil {
+		return false
+	}
+	if attempt.Request != nil {
+		if IsIdempotent(attempt.Request.Method) || attempt.Request.Header.Get(IdempotencyKeyHeader) != "" {
+			return true
+		}
+	}
+	return IsNotSent(attempt.Error)
+}
+
+// IsIdempotent Check if http method is idempotent
+func IsIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsNotSent Check if transport error happened before request was sent
+// Dial errors, DNS errors and refused connections
+func IsNotSent(err error) bool {
+	var opError *net.OpError
+	if errors.As(err, &opError) && opError.Op == "dial" {
+		return true
+	}
+	var dnsError *net.DNSError
+	if errors.As(err, &dnsError) {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// Default retry policy
+// Responses are checked with retry strategy, transport errors with IdempotentRetry
+func defaultRetryPolicy(strategy func(response *http.Response) bool) func(attempt RetryAttempt) bool {
+	return func(attempt RetryAttempt) bool {
+		if attempt.Error != nil {
+			return IdempotentRetry(attempt)
+		}
+		return strategy(attempt.Response)
+	}
+}
+
+// Generate random idempotency key in UUID v4 format
+func newIdempotencyKey() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	var s [36]byte
+	hex.Encode(s[0:8], b[0:4])
+	s[8] = '-'
+	hex.Encode(s[9:13], b[4:6])
+	s[13] = '-'
+	hex.Encode(s[14:18], b[6:8])
+	s[18] = '-'
+	hex.Encode(s[19:23], b[8:10])
+	s[23] = '-'
+	hex.Encode(s[24:], b[10:])
+	return string(s[:])
+}
+
+// Retry middleware
+// Repeats attempts according to retry policy, backoff, circuit breaker and retry budget
+// Transport errors are converted to porterr errors
+func retryMiddleware(r *HttpRequest, parent context.Context) Middleware {
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			ctx := req.Context()
+			key := requestKey(r, req)
+			//Register request in retry budget
+			if r.RetryBudget != nil {
+				r.RetryBudget.request(key)
+			}
+			var response *http.Response
+			var err error
+			r.attempts, r.exhausted = nil, false
+			//Loop for retry count
+			for i := uint(0); i <= r.RetryCount; i++ {
+				//Check circuit
+				if r.CircuitBreaker != nil && !r.CircuitBreaker.allow(key) {
+					return nil, circuitOpenError(r, key)
+				}
+				//Perform attempt
+				start := time.Now()
+				response, err = next(cloneRequest(withAttempt(ctx, AttemptInfo{Attempt: i}), req))
+				r.recordAttempt(i, response, err, time.Since(start))
+				//Report result to circuit
+				r.reportCircuit(ctx, key, response, err)
+				//If server does not respond
+				if err != nil {
+					if ctx.Err() != nil {
+						return nil, r.contextError(parent)
+					}
+					if e, ok := err.(porterr.IError); ok {
+						return nil, e
+					}
+					var readError *bodyReadError
+					if errors.As(err, &readError) {
+						if r.AttemptTimeout > 0 && errors.Is(err, context.DeadlineExceeded) {
+							return nil, timeoutError(r, "attempt", r.AttemptTimeout)
+						}
+						return nil, porterr.NewF(porterr.PortErrorResponse, "Http Response (%s) read error: %s. Service: %s", r.Host+r.Url, err, r.Label)
+					}
+					if !r.canRetry(RetryAttempt{Attempt: i, Request: req, Error: err}) {
+						if r.AttemptTimeout > 0 && errors.Is(err, context.DeadlineExceeded) {
+							return nil, timeoutError(r, "attempt", r.AttemptTimeout)
+						}
+						return nil, porterr.NewF(porterr.PortErrorSystem, "Http Request (%s) failed. Service: %s, Error: %s", r.Url, r.Label, err)
+					}
+					if e := r.wait(ctx, parent, r.retryDelay(i, nil, err)); e != nil {
+						return nil, e
+					}
+					continue
+				}
+				//Check if you can retry the response
+				if r.canRetry(RetryAttempt{Attempt: i, Request: req, Response: response}) {
+					//Sleep before next round
+					if e := r.wait(ctx, parent, r.retryDelay(i, response, nil)); e != nil {
+						return nil, e
+					}
+					continue
+				}
+				break
+			}
+			return response, nil
+		}
+	}
+}
+
+// Check if attempt can be retried
+// Retry is withdrawn from retry budget
+// Retryable attempt without retries left marks configured retries as exhausted
+func (r *HttpRequest) canRetry(attempt RetryAttempt) bool {
+	retry := r.RetryPolicy(attempt)
+	if r.RespectRetryAfter && attempt.Response != nil && attempt.Response.StatusCode == http.StatusTooManyRequests {
+		retry = true
+	}
+	if !retry {
+		return false
+	}
+	if attempt.Attempt >= r.RetryCount {
+		r.exhausted = r.RetryCount > 0
+		return false
+	}
+	if r.RetryBudget != nil && !r.RetryBudget.withdraw(requestKey(r, attempt.Request)) {
+		if r.Logger != nil {
+			r.Logger.Printf("Retry budget exhausted. Retry skipped. Service: %s", r.Label)
+		}
+		if r.StructuredLogger != nil {
+			r.StructuredLogger.LogAttrs(attempt.Request.Context(), slog.LevelWarn, "retry budget exhausted",
+				slog.String("label", r.Label), slog.Uint64("attempt", uint64(attempt.Attempt)))
+		}
+		r.exhausted = true
+		return false
+	}
+	return true
+}
+
+// Record result of attempt
+func (r *HttpRequest) recordAttempt(attempt uint, response *http.Response, err error, duration time.Duration) {
+	result := AttemptResult{Attempt: attempt, Error: err, Duration: duration}
+	if response != nil {
+		result.Status = response.StatusCode
+	}
+	var readError *bodyReadError
+	if errors.As(err, &readError) {
+		result.Status = readError.status
+	}
+	r.attempts = append(r.attempts, result)
+}
+
+// Key of request for circuit breaker and retry budget
+// Label or Host if label is empty
+func requestKey(request *HttpRequest, req *http.Request) string {
+	if request.Label != "" {
+		return request.Label
+	}
+	return req.URL.Host
+}
+
+// Delay before next attempt
+// Delay requested by server has priority over backoff strategy
+func (r *HttpRequest) retryDelay(attempt uint, response *http.Response, err error) time.Duration {
+	if r.RespectRetryAfter {
+		if delay, ok := serverRetryDelay(response, time.Now()); ok {
+			if r.MaxRetryAfter > 0 && delay > r.MaxRetryAfter {
+				delay = r.MaxRetryAfter
+			}
+			return delay
+		}
+	}
+	return r.Backoff.Delay(attempt, response, err)
+}
+
+// Wait before next attempt
+// Returns error immediately when delay exceeds context deadline
+func (r *HttpRequest) wait(ctx context.Context, parent context.Context, delay time.Duration) porterr.IError {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		if parentDeadline, ok := parent.Deadline(); ok && !parentDeadline.After(deadline) {
+			return canceledError(r, context.DeadlineExceeded)
+		}
+		return timeoutError(r, "total", r.TotalTimeout)
+	}
+	if sleep(ctx, delay) != nil {
+		return r.contextError(parent)
+	}
+	return nil
+}
+
+// Error when request context is done
+// Total timeout error if parent context is still active
+func (r *HttpRequest) contextError(parent context.Context) porterr.IError {
+	if parent.Err() != nil {
+		return canceledError(r, parent.Err())
+	}
+	return timeoutError(r, "total", r.TotalTimeout)
+}
@@ -0,0 +1,128 @@
+package goreq
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIdempotentRetry(t *testing.T) {
+	get, _ := http.NewRequest(http.MethodGet, "http://localhost", nil)
+	post, _ := http.NewRequest(http.MethodPost, "http://localhost", nil)
+	keyed, _ := http.NewRequest(http.MethodPost, "http://localhost", nil)
+	keyed.Header.Set(IdempotencyKeyHeader, newIdempotencyKey())
+	timeout := errors.New("timeout")
+	dial := &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+	if !IdempotentRetry(RetryAttempt{Request: get, Error: timeout}) {
+		t.Fatal("GET must be retried")
+	}
+	if IdempotentRetry(RetryAttempt{Request: post, Error: timeout}) {
+		t.Fatal("POST must not be retried")
+	}
+	if !IdempotentRetry(RetryAttempt{Request: keyed, Error: timeout}) {
+		t.Fatal("POST with idempotency key must be retried")
+	}
+	if !IdempotentRetry(RetryAttempt{Request: post, Error: dial}) {
+		t.Fatal("POST must be retried when it was not sent")
+	}
+	if len(newIdempotencyKey()) != 36 || newIdempotencyKey() == newIdempotencyKey() {
+		t.Fatal("wrong idempotency key")
+	}
+}
+
+func TestEnsureIdempotencyKey(t *testing.T) {
+	var mu sync.Mutex
+	var keys []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		keys = append(keys, r.Header.Get(IdempotencyKeyHeader))
+		mu.Unlock()
+		// drop connection after request is received
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		_ = conn.Close()
+	}))
+	defer s.Close()
+	req := HttpRequest{
+		Method:     http.MethodPost,
+		Url:        s.URL,
+		Body:       []byte(`{}`),
+		RetryCount: 2,
+	}
+	if _, _, err := Ensure(req); err == nil {
+		t.Fatal("error await")
+	}
+	mu.Lock()
+	if len(keys) != 1 {
+		t.Fatal("POST must not be replayed", len(keys))
+	}
+	keys = nil
+	mu.Unlock()
+	req.IdempotencyKey = true
+	if _, _, err := Ensure(req); err == nil {
+		t.Fatal("error await")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(keys) != 3 || keys[0] == "" || keys[0] != keys[1] || keys[1] != keys[2] {
+		t.Fatal("idempotency key must be stable across attempts", keys)
+	}
+	if req.Headers != nil {
+		t.Fatal("request headers must not be modified")
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	var calls int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer s.Close()
+	buffer := &bytes.Buffer{}
+	budget := &RetryBudget{Ratio: 0.5, Window: time.Minute}
+	req := HttpRequest{
+		Method:      http.MethodGet,
+		Url:         s.URL,
+		Label:       "budget",
+		RetryCount:  3,
+		RetryBudget: budget,
+		Logger:      log.New(buffer, "", 0),
+	}
+	records := &bytes.Buffer{}
+	req.StructuredLogger = slog.New(slog.NewTextHandler(records, nil))
+	other := req
+	other.Label = "other"
+	for i := 0; i < 4; i++ {
+		response, _, _ := Ensure(req)
+		if response == nil || response.StatusCode != http.StatusServiceUnavailable {
+			t.Fatal("last response must be returned")
+		}
+		_, _, _ = Ensure(other)
+	}
+	// 4, 2, 1 and 2 calls per label with default min retries
+	if calls != 18 {
+		t.Fatal("wrong count of calls", calls)
+	}
+	if !strings.Contains(buffer.String(), "Retry budget exhausted") || !strings.Contains(records.String(), "retry budget exhausted") {
+		t.Fatal("exhausted budget must be logged")
+	}
+	// Low traffic
+	atomic.StoreInt32(&calls, 0)
+	_, _, _ = Ensure(HttpRequest{Method: http.MethodGet, Url: s.URL, RetryCount: 1, RetryBudget: &RetryBudget{}})
+	if atomic.LoadInt32(&calls) != 2 {
+		t.Fatal("single request must be retried", calls)
+	}
+	budget = &RetryBudget{MinRetries: 2}
+	if !budget.withdraw("key") || !budget.withdraw("key") || budget.withdraw("key") {
+		t.Fatal("min retries must be allowed")
+	}
+}